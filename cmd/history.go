@@ -0,0 +1,89 @@
+/*
+Copyright © 2025 Ken'ichiro Oyama <k1lowxb@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/k1LoW/gh-triage/history"
+	"github.com/k1LoW/gh-triage/profile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyRun   string
+	historyLimit int
+)
+
+var historyCmd = &cobra.Command{
+	Use:          "history",
+	Short:        "Show the history of mutations performed",
+	Long:         `Show the history of mutations (done, unsubscribe, read, label, unlabel, comment, assign and request_review) performed with the profile, newest first.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := history.New(history.Path(profile.Path(profileFlag))).Entries()
+		if err != nil {
+			return err
+		}
+		if historyRun != "" {
+			entries = history.Run(entries, historyRun)
+		}
+		w := cmd.OutOrStdout()
+		for i := len(entries) - 1; i >= 0; i-- {
+			if historyLimit > 0 && len(entries)-i > historyLimit {
+				break
+			}
+			e := entries[i]
+			action := e.Action
+			if e.Undo != "" {
+				action = "undo " + action
+			}
+			if e.Manual {
+				action += " (manual)"
+			}
+			ref := e.Owner + "/" + e.Repo
+			if e.Number > 0 {
+				ref += fmt.Sprintf(" #%d", e.Number)
+			}
+			if _, err := fmt.Fprintf(w, "%s [%s] %-14s %s\n  %s ( %s )\n", e.Time.Format("2006-01-02 15:04:05"), e.RunID, action, ref, e.Title, e.HTMLURL); err != nil {
+				return err
+			}
+			if e.Undo != "" {
+				if _, err := fmt.Fprintf(w, "  undo of run: %s\n", e.Undo); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "  rule: %s, condition: %s\n", e.Rule, e.Condition); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().StringVarP(&historyRun, "run", "r", "", "Show only the mutations of the run")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "l", 50, "Maximum number of mutations to show (0 for all)")
+}
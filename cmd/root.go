@@ -27,12 +27,16 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/k1LoW/duration"
 	"github.com/k1LoW/gh-triage/gh"
+	"github.com/k1LoW/gh-triage/history"
+	"github.com/k1LoW/gh-triage/httpcache"
 	"github.com/k1LoW/gh-triage/profile"
+	"github.com/k1LoW/gh-triage/state"
 	"github.com/k1LoW/gh-triage/version"
 	"github.com/mattn/go-colorable"
 	"github.com/spf13/cobra"
@@ -43,6 +47,17 @@ var (
 	watch        bool
 	intervalFlag string
 	verbose      bool
+	dryRun       bool
+	format       string
+	templateFlag string
+
+	allFlag           bool
+	participatingFlag bool
+	sinceFlag         string
+	beforeFlag        string
+	concurrencyFlag   int
+	failFast          bool
+	noCache           bool
 )
 
 var rootCmd = &cobra.Command{
@@ -57,7 +72,46 @@ var rootCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		c, err := gh.New(cfg, colorable.NewColorableStdout(), verbose)
+		// Fetch filters specified by flags take precedence over the profile
+		if cmd.Flags().Changed("all") {
+			cfg.All = allFlag
+		}
+		if cmd.Flags().Changed("participating") {
+			cfg.Participating = participatingFlag
+		}
+		if cmd.Flags().Changed("since") {
+			cfg.Since = sinceFlag
+		}
+		if cmd.Flags().Changed("before") {
+			cfg.Before = beforeFlag
+		}
+		if cmd.Flags().Changed("concurrency") {
+			if concurrencyFlag <= 0 {
+				return fmt.Errorf("invalid concurrency: %d", concurrencyFlag)
+			}
+			cfg.Concurrency = concurrencyFlag
+		}
+		if _, err := cfg.SinceTime(time.Now()); err != nil {
+			return err
+		}
+		if _, err := cfg.BeforeTime(time.Now()); err != nil {
+			return err
+		}
+		st, err := state.Open(state.Path(profile.Path(profileFlag)))
+		if err != nil {
+			return err
+		}
+		opts := []gh.Option{gh.WithDryRun(dryRun), gh.WithFormat(format), gh.WithTemplate(templateFlag), gh.WithFailFast(failFast), gh.WithStore(st), gh.WithHistory(history.New(history.Path(profile.Path(profileFlag))))}
+		if !noCache {
+			cache, err := httpcache.Open(httpcache.Dir())
+			if err != nil {
+				// The cache only saves requests, so triage goes on without it.
+				slog.Warn("Failed to open the cache, continuing without it", "error", err)
+			} else {
+				opts = append(opts, gh.WithCache(cache))
+			}
+		}
+		c, err := gh.New(cfg, colorable.NewColorableStdout(), verbose, opts...)
 		if err != nil {
 			return err
 		}
@@ -92,9 +146,19 @@ var rootCmd = &cobra.Command{
 			ticker := time.NewTicker(interval)
 			defer ticker.Stop()
 
+			// Honor the poll interval requested by the server as the minimum interval
+			resetTicker := func() {
+				if pi := c.PollInterval(); pi > interval {
+					ticker.Reset(pi)
+				} else {
+					ticker.Reset(interval)
+				}
+			}
+
 			if err := c.Triage(ctx); err != nil {
 				slog.Error("Triage failed", "error", err)
 			}
+			resetTicker()
 
 			for {
 				select {
@@ -103,6 +167,7 @@ var rootCmd = &cobra.Command{
 						slog.Error("Triage failed", "error", err)
 						// Continue watching even if triage fails (error continuation strategy)
 					}
+					resetTicker()
 				case <-ctx.Done():
 					slog.Info("Watch mode stopped")
 					return nil
@@ -130,4 +195,14 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&watch, "watch", "w", false, "Watch for notifications")
 	rootCmd.PersistentFlags().StringVarP(&intervalFlag, "interval", "i", "5min", "Interval for watching notifications (e.g., 5min, 1hour)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "V", false, "Verbose output")
+	rootCmd.PersistentFlags().StringVarP(&format, "format", "f", gh.FormatText, fmt.Sprintf("Output format of the list action (%s)", strings.Join(gh.Formats, ", ")))
+	rootCmd.PersistentFlags().StringVarP(&templateFlag, "template", "t", "", "Go text/template to render each item of the list action (overrides list.template in the profile)")
+	rootCmd.PersistentFlags().BoolVarP(&dryRun, "dry-run", "n", false, "Show which actions would be performed without performing them")
+	rootCmd.PersistentFlags().BoolVarP(&allFlag, "all", "a", false, "Fetch read notifications too (overrides all in the profile)")
+	rootCmd.PersistentFlags().BoolVar(&participatingFlag, "participating", false, "Fetch only notifications in which you are directly participating or mentioned (overrides participating in the profile)")
+	rootCmd.PersistentFlags().StringVar(&sinceFlag, "since", "", "Fetch only notifications updated after the time, such as 2025-01-01 or 7d (overrides since in the profile)")
+	rootCmd.PersistentFlags().StringVar(&beforeFlag, "before", "", "Fetch only notifications updated before the time, such as 2025-01-01 or 7d (overrides before in the profile)")
+	rootCmd.PersistentFlags().IntVarP(&concurrencyFlag, "concurrency", "c", 8, "Number of notifications processed at the same time (overrides concurrency in the profile)")
+	rootCmd.PersistentFlags().BoolVar(&failFast, "fail-fast", false, "Stop at the first notification that fails to be processed")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Do not use the on-disk cache of API responses")
 }
@@ -0,0 +1,59 @@
+/*
+Copyright © 2025 Ken'ichiro Oyama <k1lowxb@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/k1LoW/gh-triage/gh"
+	"github.com/k1LoW/gh-triage/history"
+	"github.com/k1LoW/gh-triage/profile"
+	"github.com/mattn/go-colorable"
+	"github.com/spf13/cobra"
+)
+
+var undoRun string
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Undo the mutations of the last run",
+	Long: `Undo the mutations of the last run (or the run specified by --run) recorded in the history.
+Unsubscribed threads are resubscribed, and labels, assignees and review requests are reverted.
+Threads marked as done or read and comments can not be reverted through the API, so their URLs are shown to re-surface them by hand.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := profile.Load(profileFlag)
+		if err != nil {
+			return err
+		}
+		h := history.New(history.Path(profile.Path(profileFlag)))
+		c, err := gh.New(cfg, colorable.NewColorableStdout(), verbose, gh.WithDryRun(dryRun), gh.WithHistory(h))
+		if err != nil {
+			return err
+		}
+		return c.Undo(cmd.Context(), undoRun)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+	undoCmd.Flags().StringVarP(&undoRun, "run", "r", "", "ID of the run to undo (default: the last run)")
+}
@@ -0,0 +1,234 @@
+// Package cond provides the environment and evaluation of conditions written in expr-lang.
+package cond
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/vm"
+	"github.com/samber/lo"
+)
+
+var programs sync.Map // Cache of compiled programs keyed by condition
+
+// NewEnv returns a new environment that has every field available in conditions with its default value.
+func NewEnv() map[string]any {
+	return map[string]any{
+		// Notification
+		"me":           "",
+		"my_teams":     []string{},
+		"my_orgs":      []string{},
+		"title":        "",
+		"owner":        "",
+		"repo":         "",
+		"thread_id":    "",
+		"reason":       "",
+		"subject_type": "",
+		"updated_at":   time.Time{},
+		"last_read_at": time.Time{},
+		"unread":       true,
+
+		// Subject type
+		"is_issue":                 false,
+		"is_pull_request":          false,
+		"is_discussion":            false,
+		"is_release":               false,
+		"is_check_suite":           false,
+		"is_workflow_run":          false,
+		"is_commit":                false,
+		"is_vulnerability_alert":   false,
+		"is_dependabot_alert":      false,
+		"is_security_advisory":     false,
+		"is_repository_invitation": false,
+
+		// Issue, Pull Request and Discussion
+		"number":     -1,
+		"state":      "unknown",
+		"open":       false,
+		"closed":     false,
+		"labels":     []string{},
+		"assignees":  []string{},
+		"author":     "",
+		"html_url":   "",
+		"created_at": time.Time{},
+		"closed_at":  time.Time{},
+		"answered":   false,
+
+		// Pull Request
+		"draft":             false,
+		"merged":            false,
+		"merged_at":         time.Time{},
+		"mergeable":         false,
+		"mergeable_state":   "unknown",
+		"reviewers":         []string{},
+		"review_teams":      []string{},
+		"review_team_slugs": []string{},
+		"approved":          false,
+		"review_states":     []string{},
+		"status_passed":     false,
+		"checks_passed":     false,
+		"passed":            false,
+		"failed":            false,
+		"in_progress":       false,
+
+		// CheckSuite, WorkflowRun, Commit and SecurityAdvisory
+		"workflow":   "",
+		"branch":     "",
+		"conclusion": "",
+		"sha":        "",
+		"ghsa_id":    "",
+
+		// Release
+		"release_id":       -1,
+		"tag_name":         "",
+		"target_commitish": "",
+		"prerelease":       false,
+		"published_at":     time.Time{},
+	}
+}
+
+// Compile compiles a single condition against the typed environment.
+// Compiled programs are cached, so compiling the same condition again is cheap.
+func Compile(cond string) (*vm.Program, error) {
+	if cond == "*" {
+		cond = "true"
+	}
+	if v, ok := programs.Load(cond); ok {
+		if p, ok := v.(*vm.Program); ok {
+			return p, nil
+		}
+	}
+	opts := append([]expr.Option{expr.Env(NewEnv()), expr.AsBool()}, options...)
+	p, err := expr.Compile(cond, opts...)
+	if err != nil {
+		return nil, err
+	}
+	programs.Store(cond, p)
+	return p, nil
+}
+
+// Eval evaluates a single condition against the fields.
+func Eval(cond string, m map[string]any) (bool, error) {
+	p, err := Compile(cond)
+	if err != nil {
+		return false, err
+	}
+	v, err := expr.Run(p, m)
+	if err != nil {
+		return false, err
+	}
+	tf, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition did not evaluate to boolean: %v (%T)", v, v)
+	}
+	return tf, nil
+}
+
+// Match reports whether any of the conditions matches the fields.
+func Match(conds []string, m map[string]any) bool {
+	if len(conds) == 0 {
+		return false
+	}
+	joined := "(" + strings.Join(lo.Map(conds, func(cond string, _ int) string {
+		if cond == "*" {
+			return "true"
+		}
+		return cond
+	}), ") || (") + ")"
+	tf, err := Eval(joined, m)
+	if err != nil {
+		slog.Error("Failed to evaluate condition", "cond", joined, "error", err)
+		return false
+	}
+	return tf
+}
+
+// Matched returns the first condition that matches the fields.
+func Matched(conds []string, m map[string]any) (string, bool) {
+	for _, c := range conds {
+		tf, err := Eval(c, m)
+		if err != nil {
+			slog.Error("Failed to evaluate condition", "cond", c, "error", err)
+			continue
+		}
+		if tf {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// Fields returns the names of the fields referenced by the conditions.
+// If a condition refers to the whole environment through $env, every field is returned.
+func Fields(conds []string) ([]string, error) {
+	env := NewEnv()
+	v := &fieldsVisitor{env: env}
+	for _, c := range conds {
+		p, err := Compile(c)
+		if err != nil {
+			return nil, err
+		}
+		node := p.Node()
+		ast.Walk(&node, v)
+	}
+	if v.all {
+		return lo.Keys(env), nil
+	}
+	return lo.Uniq(v.fields), nil
+}
+
+// TimeDependent reports whether the conditions call functions whose results change over time, such as older_than and business_hours.
+func TimeDependent(conds []string) (bool, error) {
+	v := &callsVisitor{}
+	for _, c := range conds {
+		p, err := Compile(c)
+		if err != nil {
+			return false, err
+		}
+		node := p.Node()
+		ast.Walk(&node, v)
+	}
+	return lo.Some(v.calls, timeFuncs), nil
+}
+
+// fieldsVisitor collects the identifiers of fields in the AST of conditions.
+type fieldsVisitor struct {
+	env    map[string]any
+	fields []string
+	all    bool
+}
+
+func (v *fieldsVisitor) Visit(node *ast.Node) {
+	id, ok := (*node).(*ast.IdentifierNode)
+	if !ok {
+		return
+	}
+	if id.Value == "$env" {
+		v.all = true
+		return
+	}
+	if _, ok := v.env[id.Value]; ok {
+		v.fields = append(v.fields, id.Value)
+	}
+}
+
+// callsVisitor collects the names of functions called in the AST of conditions.
+type callsVisitor struct {
+	calls []string
+}
+
+func (v *callsVisitor) Visit(node *ast.Node) {
+	switch n := (*node).(type) {
+	case *ast.CallNode:
+		if id, ok := n.Callee.(*ast.IdentifierNode); ok {
+			v.calls = append(v.calls, id.Value)
+		}
+	case *ast.BuiltinNode:
+		v.calls = append(v.calls, n.Name)
+	}
+}
@@ -0,0 +1,206 @@
+package cond
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestEval(t *testing.T) {
+	m := map[string]any{
+		"merged": true,
+		"closed": false,
+		"labels": []string{"bug"},
+		"number": 1,
+	}
+	tests := []struct {
+		cond    string
+		want    bool
+		wantErr bool
+	}{
+		{"*", true, false},
+		{"merged", true, false},
+		{"closed", false, false},
+		{"'bug' in labels", true, false},
+		{"number", false, true},
+		{"merged &&", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cond, func(t *testing.T) {
+			got, err := Eval(tt.cond, m)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval(%q) error = %v, wantErr %v", tt.cond, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Eval(%q) = %v, want %v", tt.cond, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	m := map[string]any{
+		"merged": false,
+		"closed": true,
+	}
+	tests := []struct {
+		name string
+		cond []string
+		want bool
+	}{
+		{"empty", []string{}, false},
+		{"any matches", []string{"merged", "closed"}, true},
+		{"none matches", []string{"merged", "!closed"}, false},
+		{"wildcard", []string{"*"}, true},
+		{"invalid", []string{"merged &&"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tt.cond, m); got != tt.want {
+				t.Errorf("Match(%v) = %v, want %v", tt.cond, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatched(t *testing.T) {
+	m := map[string]any{
+		"merged": false,
+		"closed": true,
+	}
+	tests := []struct {
+		name   string
+		cond   []string
+		want   string
+		wantOK bool
+	}{
+		{"empty", []string{}, "", false},
+		{"first match", []string{"merged", "closed", "*"}, "closed", true},
+		{"none matches", []string{"merged", "!closed"}, "", false},
+		{"skip invalid", []string{"merged &&", "*"}, "*", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Matched(tt.cond, m)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Matched(%v) = (%q, %v), want (%q, %v)", tt.cond, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		conds   []string
+		want    []string
+		wantErr bool
+	}{
+		{"wildcard", []string{"*"}, []string{}, false},
+		{"notification only", []string{"repo == 'gh-triage' && reason == 'mention'", "title contains 'WIP'"}, []string{"reason", "repo", "title"}, false},
+		{"predicate", []string{"any(labels, # == 'bug') && !passed", "let x = approved; x"}, []string{"approved", "labels", "passed"}, false},
+		{"function", []string{"older_than(updated_at, '7d')"}, []string{"updated_at"}, false},
+		{"invalid", []string{"merged &&"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Fields(tt.conds)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fields(%v) error = %v, wantErr %v", tt.conds, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Fields(%v) = %v, want %v", tt.conds, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("$env", func(t *testing.T) {
+		got, err := Fields([]string{"$env.passed"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(NewEnv()) {
+			t.Errorf("Expected every field, got %v", got)
+		}
+	})
+}
+
+func TestTimeDependent(t *testing.T) {
+	tests := []struct {
+		name  string
+		conds []string
+		want  bool
+	}{
+		{"fields only", []string{"*", "is_pull_request && !approved", "updated_at > last_read_at"}, false},
+		{"older_than", []string{"is_issue", "open && older_than(updated_at, '7d')"}, true},
+		{"business_hours", []string{"business_hours()"}, true},
+		{"since", []string{"created_at > since('1d')"}, true},
+		{"builtin now", []string{"now().Sub(updated_at).Hours() > 24"}, true},
+		{"predicate", []string{"any(labels, # == 'bug')"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TimeDependent(tt.conds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("TimeDependent(%v) = %v, want %v", tt.conds, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeHelpers(t *testing.T) {
+	// Wednesday
+	current := time.Date(2025, 7, 30, 10, 0, 0, 0, time.Local)
+	orig := now
+	t.Cleanup(func() {
+		now = orig
+	})
+	now = func() time.Time { return current }
+
+	m := map[string]any{
+		"created_at": current.Add(-5 * 24 * time.Hour),
+		"merged_at":  current.Add(-2 * time.Hour),
+		"closed_at":  time.Time{},
+	}
+	tests := []struct {
+		cond    string
+		want    bool
+		wantErr bool
+	}{
+		{"older_than(created_at, '3d')", true, false},
+		{"older_than(merged_at, '3d')", false, false},
+		{"older_than(closed_at, '3d')", false, false},
+		{"newer_than(merged_at, '1day')", true, false},
+		{"newer_than(closed_at, '1day')", false, false},
+		{"age(created_at) > duration('96h')", true, false},
+		{"age(closed_at) == duration('0s')", true, false},
+		{"merged_at > since('3h')", true, false},
+		{"created_at > since('1w')", true, false},
+		{"business_hours()", true, false},
+		{"older_than(created_at, 'invalid')", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cond, func(t *testing.T) {
+			got, err := Eval(tt.cond, m)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval(%q) error = %v, wantErr %v", tt.cond, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Eval(%q) = %v, want %v", tt.cond, got, tt.want)
+			}
+		})
+	}
+
+	// Saturday
+	current = time.Date(2025, 8, 2, 10, 0, 0, 0, time.Local)
+	if got, err := Eval("business_hours()", m); err != nil || got {
+		t.Errorf("business_hours() on Saturday = %v, %v, want false", got, err)
+	}
+}
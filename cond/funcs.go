@@ -0,0 +1,78 @@
+package cond
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/k1LoW/duration"
+)
+
+// now returns the current time. It is a variable for testing.
+var now = time.Now
+
+// timeFuncs are the functions whose results change over time even if the fields do not, including the builtin now.
+var timeFuncs = []string{"age", "since", "older_than", "newer_than", "business_hours", "now"}
+
+// options are the options for compiling conditions, including the time-aware helper functions.
+var options = []expr.Option{
+	expr.Function("age", func(params ...any) (any, error) {
+		t, ok := params[0].(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("age: invalid argument type: %T", params[0])
+		}
+		if t.IsZero() {
+			return time.Duration(0), nil
+		}
+		return now().Sub(t), nil
+	}, new(func(time.Time) time.Duration)),
+	expr.Function("since", func(params ...any) (any, error) {
+		d, err := parseDuration(params[0])
+		if err != nil {
+			return nil, fmt.Errorf("since: %w", err)
+		}
+		return now().Add(-d), nil
+	}, new(func(string) time.Time)),
+	expr.Function("older_than", func(params ...any) (any, error) {
+		t, ok := params[0].(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("older_than: invalid argument type: %T", params[0])
+		}
+		d, err := parseDuration(params[1])
+		if err != nil {
+			return nil, fmt.Errorf("older_than: %w", err)
+		}
+		return !t.IsZero() && now().Sub(t) > d, nil
+	}, new(func(time.Time, string) bool)),
+	expr.Function("newer_than", func(params ...any) (any, error) {
+		t, ok := params[0].(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("newer_than: invalid argument type: %T", params[0])
+		}
+		d, err := parseDuration(params[1])
+		if err != nil {
+			return nil, fmt.Errorf("newer_than: %w", err)
+		}
+		return !t.IsZero() && now().Sub(t) < d, nil
+	}, new(func(time.Time, string) bool)),
+	expr.Function("business_hours", func(params ...any) (any, error) {
+		t := now()
+		if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+			return false, nil
+		}
+		return t.Hour() >= 9 && t.Hour() < 18, nil
+	}, new(func() bool)),
+}
+
+// parseDuration parses a duration such as 3d or 12hours using the same parser as the --interval option.
+func parseDuration(v any) (time.Duration, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid duration type: %T", v)
+	}
+	d, err := duration.Parse(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,297 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/samber/lo"
+	"github.com/shurcooL/githubv4"
+)
+
+// batchSize is the maximum number of subjects fetched in a single GraphQL query.
+const batchSize = 50
+
+// batchSubject is the pull request or issue of a notification fetched in a batched GraphQL query.
+type batchSubject struct {
+	issue       *batchIssue
+	pullRequest *batchPullRequest
+}
+
+type batchActor struct {
+	Login string
+}
+
+type batchLabels struct {
+	Nodes []struct {
+		Name string
+	}
+}
+
+type batchAssignees struct {
+	Nodes []batchActor
+}
+
+type batchReview struct {
+	State       string
+	SubmittedAt *time.Time
+}
+
+// batchIssue is the fields of an issue fetched in a batched GraphQL query.
+type batchIssue struct {
+	State     string
+	URL       string
+	CreatedAt time.Time
+	ClosedAt  *time.Time
+	Author    *batchActor
+	Labels    batchLabels    `graphql:"labels(first: 100)"`
+	Assignees batchAssignees `graphql:"assignees(first: 100)"`
+}
+
+// batchPullRequest is the fields of a pull request fetched in a batched GraphQL query.
+type batchPullRequest struct {
+	State            string
+	URL              string
+	IsDraft          bool
+	Merged           bool
+	Mergeable        string
+	MergeStateStatus string
+	CreatedAt        time.Time
+	ClosedAt         *time.Time
+	MergedAt         *time.Time
+	Author           *batchActor
+	Labels           batchLabels    `graphql:"labels(first: 100)"`
+	Assignees        batchAssignees `graphql:"assignees(first: 100)"`
+	ReviewRequests   struct {
+		Nodes []struct {
+			RequestedReviewer *struct {
+				User struct {
+					Login string
+				} `graphql:"... on User"`
+				Team struct {
+					Name string
+					Slug string
+				} `graphql:"... on Team"`
+			}
+		}
+	} `graphql:"reviewRequests(first: 100)"`
+	Reviews struct {
+		Nodes []batchReview
+	} `graphql:"reviews(first: 100) @include(if: $reviews)"`
+	Commits struct {
+		Nodes []struct {
+			Commit struct {
+				StatusCheckRollup *struct {
+					Contexts struct {
+						Nodes []struct {
+							Typename string `graphql:"__typename"`
+							CheckRun struct {
+								Status     string
+								Conclusion string
+							} `graphql:"... on CheckRun"`
+							StatusContext struct {
+								State string
+							} `graphql:"... on StatusContext"`
+						}
+					} `graphql:"contexts(first: 100)"`
+				}
+			}
+		}
+	} `graphql:"commits(last: 1) @include(if: $statuses)"`
+}
+
+// batchRef is the reference of a pull request or an issue to fetch.
+type batchRef struct {
+	url    string // API URL of the subject of the notification
+	owner  string
+	repo   string
+	number int
+	pull   bool
+}
+
+// prefetch fetches the pull requests and issues of the notifications in batched GraphQL queries, keyed by the subject URL.
+// Subjects that fail to be fetched are not included, so that they are fetched with the REST API.
+func (c *Client) prefetch(ctx context.Context, notifications []*github.Notification) map[string]*batchSubject {
+	var refs []batchRef
+	for _, n := range notifications {
+		subjectType := n.GetSubject().GetType()
+		if subjectType != "Issue" && subjectType != "PullRequest" {
+			continue
+		}
+		u, err := url.Parse(n.GetSubject().GetURL())
+		if err != nil {
+			continue
+		}
+		number, err := strconv.Atoi(path.Base(u.Path))
+		if err != nil {
+			continue
+		}
+		refs = append(refs, batchRef{
+			url:    n.GetSubject().GetURL(),
+			owner:  n.GetRepository().GetOwner().GetLogin(),
+			repo:   n.GetRepository().GetName(),
+			number: number,
+			pull:   subjectType == "PullRequest",
+		})
+	}
+	batch := map[string]*batchSubject{}
+	for chunk := range slices.Chunk(refs, batchSize) {
+		if err := c.fetchBatch(ctx, chunk, batch); err != nil && c.verbose {
+			slog.Warn("Failed to fetch subjects in a batch, falling back to REST API", "error", err)
+		}
+	}
+	return batch
+}
+
+// fetchBatch fetches the subjects in a single aliased GraphQL query and stores them in the batch.
+// Subjects fetched are stored even if the query returns errors for the others.
+func (c *Client) fetchBatch(ctx context.Context, refs []batchRef, batch map[string]*batchSubject) error {
+	fields := make([]reflect.StructField, 0, len(refs))
+	// Reviews and statuses are fetched only if they are used.
+	variables := map[string]any{
+		"reviews":  githubv4.Boolean(c.needs.reviews),
+		"statuses": githubv4.Boolean(c.needs.statuses),
+	}
+	for i, r := range refs {
+		subject := reflect.StructField{
+			Name: "Issue",
+			Type: reflect.TypeFor[*batchIssue](),
+			Tag:  reflect.StructTag(fmt.Sprintf(`graphql:"issue(number: $number%d)"`, i)),
+		}
+		if r.pull {
+			subject = reflect.StructField{
+				Name: "PullRequest",
+				Type: reflect.TypeFor[*batchPullRequest](),
+				Tag:  reflect.StructTag(fmt.Sprintf(`graphql:"pullRequest(number: $number%d)"`, i)),
+			}
+		}
+		fields = append(fields, reflect.StructField{
+			Name: fmt.Sprintf("S%d", i),
+			Type: reflect.PointerTo(reflect.StructOf([]reflect.StructField{subject})),
+			Tag:  reflect.StructTag(fmt.Sprintf(`graphql:"s%d: repository(owner: $owner%d, name: $name%d)"`, i, i, i)),
+		})
+		variables[fmt.Sprintf("owner%d", i)] = githubv4.String(r.owner)
+		variables[fmt.Sprintf("name%d", i)] = githubv4.String(r.repo)
+		variables[fmt.Sprintf("number%d", i)] = githubv4.Int(int32(r.number)) //nolint:gosec
+	}
+	q := reflect.New(reflect.StructOf(fields))
+	err := c.v4Client.Query(ctx, q.Interface(), variables)
+	for i, r := range refs {
+		repository := q.Elem().Field(i)
+		if repository.IsNil() {
+			continue
+		}
+		subject := repository.Elem().Field(0)
+		if subject.IsNil() {
+			continue
+		}
+		switch s := subject.Interface().(type) {
+		case *batchIssue:
+			batch[r.url] = &batchSubject{issue: s}
+		case *batchPullRequest:
+			batch[r.url] = &batchSubject{pullRequest: s}
+		}
+	}
+	return err
+}
+
+// apply sets the fields of the issue in the same form as the REST API.
+func (i *batchIssue) apply(m map[string]any) {
+	m["state"] = strings.ToLower(i.State)
+	m["open"] = i.State == "OPEN"
+	m["closed"] = i.ClosedAt != nil
+	m["created_at"] = i.CreatedAt
+	m["closed_at"] = lo.FromPtr(i.ClosedAt)
+	m["labels"] = i.Labels.names()
+	m["assignees"] = i.Assignees.logins()
+	m["author"] = lo.FromPtr(i.Author).Login
+	m["html_url"] = i.URL
+}
+
+// apply sets the fields of the pull request in the same form as the REST API.
+// Reviews and statuses are set only if they are needed, as they are not fetched otherwise.
+func (p *batchPullRequest) apply(m map[string]any, owner string, needs fieldNeeds) {
+	// Merged pull requests are closed in the REST API.
+	m["state"] = lo.Ternary(p.State == "OPEN", "open", "closed")
+	m["open"] = p.State == "OPEN"
+	m["draft"] = p.IsDraft
+	m["merged"] = p.Merged
+	m["mergeable"] = p.Mergeable == "MERGEABLE"
+	m["mergeable_state"] = strings.ToLower(p.MergeStateStatus)
+	m["closed"] = p.ClosedAt != nil
+	m["created_at"] = p.CreatedAt
+	m["closed_at"] = lo.FromPtr(p.ClosedAt)
+	m["merged_at"] = lo.FromPtr(p.MergedAt)
+	m["labels"] = p.Labels.names()
+	reviewers := []string{}
+	reviewTeams := []string{}
+	reviewTeamSlugs := []string{}
+	for _, r := range p.ReviewRequests.Nodes {
+		switch {
+		case r.RequestedReviewer == nil:
+		case r.RequestedReviewer.User.Login != "":
+			reviewers = append(reviewers, r.RequestedReviewer.User.Login)
+		case r.RequestedReviewer.Team.Slug != "":
+			reviewTeams = append(reviewTeams, r.RequestedReviewer.Team.Name)
+			reviewTeamSlugs = append(reviewTeamSlugs, owner+"/"+r.RequestedReviewer.Team.Slug)
+		}
+	}
+	m["reviewers"] = reviewers
+	m["review_teams"] = reviewTeams
+	m["review_team_slugs"] = reviewTeamSlugs
+	m["assignees"] = p.Assignees.logins()
+	m["author"] = lo.FromPtr(p.Author).Login
+	m["html_url"] = p.URL
+
+	if needs.reviews {
+		reviews := slices.Clone(p.Reviews.Nodes)
+		slices.SortStableFunc(reviews, func(a, b batchReview) int {
+			return lo.FromPtr(a.SubmittedAt).Compare(lo.FromPtr(b.SubmittedAt))
+		})
+		setReviews(m, lo.Map(reviews, func(r batchReview, _ int) string {
+			return r.State
+		}))
+	}
+	if !needs.statuses {
+		return
+	}
+	var statuses []string
+	var checkRuns []checkRunState
+	for _, n := range p.Commits.Nodes {
+		if n.Commit.StatusCheckRollup == nil {
+			continue
+		}
+		for _, rc := range n.Commit.StatusCheckRollup.Contexts.Nodes {
+			switch rc.Typename {
+			case "CheckRun":
+				checkRuns = append(checkRuns, checkRunState{
+					status:     strings.ToLower(rc.CheckRun.Status),
+					conclusion: strings.ToLower(rc.CheckRun.Conclusion),
+				})
+			case "StatusContext":
+				statuses = append(statuses, strings.ToLower(rc.StatusContext.State))
+			}
+		}
+	}
+	setStatuses(m, statuses, checkRuns)
+}
+
+func (l batchLabels) names() []string {
+	return lo.Map(l.Nodes, func(n struct{ Name string }, _ int) string {
+		return n.Name
+	})
+}
+
+func (a batchAssignees) logins() []string {
+	return lo.Map(a.Nodes, func(n batchActor, _ int) string {
+		return n.Login
+	})
+}
@@ -0,0 +1,152 @@
+package gh
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/k1LoW/gh-triage/profile"
+)
+
+func TestSetStatuses(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []string
+		checkRuns  []checkRunState
+		passed     bool
+		failed     bool
+		inProgress bool
+	}{
+		{"empty", nil, nil, true, false, false},
+		{"success", []string{"success"}, []checkRunState{{"completed", "success"}, {"completed", "skipped"}}, true, false, false},
+		{"status pending", []string{"success", "pending"}, nil, false, false, true},
+		{"status failure", []string{"pending", "failure"}, nil, false, true, true},
+		{"check in progress", nil, []checkRunState{{"completed", "success"}, {"in_progress", ""}}, false, false, true},
+		{"check failure", nil, []checkRunState{{"in_progress", ""}, {"completed", "failure"}}, false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := map[string]any{}
+			setStatuses(m, tt.statuses, tt.checkRuns)
+			if m["passed"] != tt.passed || m["failed"] != tt.failed || m["in_progress"] != tt.inProgress {
+				t.Errorf("got passed=%v failed=%v in_progress=%v", m["passed"], m["failed"], m["in_progress"])
+			}
+		})
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/graphql" {
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var req struct {
+			Query     string
+			Variables map[string]any
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		for _, s := range []string{"s0: repository(owner: $owner0, name: $name0){pullRequest(number: $number0)", "s1: repository(owner: $owner1, name: $name1){issue(number: $number1)"} {
+			if !strings.Contains(req.Query, s) {
+				t.Errorf("Expected the query to contain %q, got %q", s, req.Query)
+			}
+		}
+		if req.Variables["number1"] != float64(2) {
+			t.Errorf("got variables %v", req.Variables)
+		}
+		// The issue is not accessible, so it is fetched with the REST API.
+		_, _ = io.WriteString(w, `{
+  "data": {"s0": {"pullRequest": {
+    "state": "MERGED",
+    "url": "https://github.com/owner/repo/pull/1",
+    "isDraft": false,
+    "merged": true,
+    "mergeable": "UNKNOWN",
+    "mergeStateStatus": "UNKNOWN",
+    "createdAt": "2025-01-01T00:00:00Z",
+    "closedAt": "2025-01-02T00:00:00Z",
+    "mergedAt": "2025-01-02T00:00:00Z",
+    "author": {"login": "alice"},
+    "labels": {"nodes": [{"name": "bug"}]},
+    "assignees": {"nodes": [{"login": "bob"}]},
+    "reviewRequests": {"nodes": [
+      {"requestedReviewer": {"login": "carol"}},
+      {"requestedReviewer": {"name": "Core", "slug": "core"}},
+      {"requestedReviewer": null}
+    ]},
+    "reviews": {"nodes": [
+      {"state": "APPROVED", "submittedAt": "2025-01-01T02:00:00Z"},
+      {"state": "COMMENTED", "submittedAt": "2025-01-01T01:00:00Z"},
+      {"state": "CHANGES_REQUESTED", "submittedAt": "2025-01-01T03:00:00Z"}
+    ]},
+    "commits": {"nodes": [{"commit": {"statusCheckRollup": {"contexts": {"nodes": [
+      {"__typename": "StatusContext", "state": "SUCCESS"},
+      {"__typename": "CheckRun", "status": "IN_PROGRESS", "conclusion": null}
+    ]}}}}]}
+  }}, "s1": null},
+  "errors": [{"message": "Could not resolve to a Repository"}]
+}`)
+	}))
+	defer ts.Close()
+	c := newTestClient(t, &profile.Profile{}, ts, io.Discard)
+	notification := func(subjectType, u string) *github.Notification {
+		return &github.Notification{
+			Repository: &github.Repository{Name: github.Ptr("repo"), Owner: &github.User{Login: github.Ptr("owner")}},
+			Subject:    &github.NotificationSubject{Type: github.Ptr(subjectType), URL: github.Ptr(u)},
+		}
+	}
+	batch := c.prefetch(context.Background(), []*github.Notification{
+		notification("PullRequest", "https://api.github.com/repos/owner/repo/pulls/1"),
+		notification("Issue", "https://api.github.com/repos/owner/repo/issues/2"),
+		notification("Release", "https://api.github.com/repos/owner/repo/releases/3"),
+	})
+	if len(batch) != 1 {
+		t.Fatalf("got %v", batch)
+	}
+	b, ok := batch["https://api.github.com/repos/owner/repo/pulls/1"]
+	if !ok || b.pullRequest == nil {
+		t.Fatalf("got %+v", b)
+	}
+	m := map[string]any{}
+	b.pullRequest.apply(m, "owner", allNeeds)
+	want := map[string]any{
+		"state":             "closed",
+		"open":              false,
+		"draft":             false,
+		"merged":            true,
+		"mergeable":         false,
+		"mergeable_state":   "unknown",
+		"closed":            true,
+		"created_at":        time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		"closed_at":         time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		"merged_at":         time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		"labels":            []string{"bug"},
+		"assignees":         []string{"bob"},
+		"reviewers":         []string{"carol"},
+		"review_teams":      []string{"Core"},
+		"review_team_slugs": []string{"owner/core"},
+		"author":            "alice",
+		"html_url":          "https://github.com/owner/repo/pull/1",
+		"approved":          true,
+		"review_states":     []string{"COMMENTED", "APPROVED"},
+		"status_passed":     true,
+		"checks_passed":     false,
+		"passed":            false,
+		"failed":            false,
+		"in_progress":       true,
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v, want %v", m, want)
+	}
+}
@@ -0,0 +1,101 @@
+package gh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/samber/lo"
+)
+
+// maxReportedItemErrors is the maximum number of item errors listed in the report.
+const maxReportedItemErrors = 10
+
+// errSkipped is returned by an action that had nothing to do, such as notify for a thread already announced,
+// so that the notification does not count toward max.
+var errSkipped = errors.New("skipped")
+
+// ItemError is an error of processing a notification.
+type ItemError struct {
+	ThreadID string // ID of the notification thread
+	Subject  string // Repository and title of the subject
+	Err      error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("%s (thread %s): %v", e.Subject, e.ThreadID, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// TriageError is the aggregated errors of notifications that failed to be processed.
+// The other notifications are processed even if some of them fail, unless fail-fast is enabled.
+type TriageError struct {
+	Total  int // Number of notifications processed
+	Errors []*ItemError
+}
+
+func (e *TriageError) Error() string {
+	counts := e.Counts()
+	kinds := lo.Keys(counts)
+	slices.SortFunc(kinds, func(a, b string) int {
+		if counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return strings.Compare(a, b)
+	})
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "failed to process %d of %d notifications (%s)", len(e.Errors), e.Total, strings.Join(lo.Map(kinds, func(k string, _ int) string {
+		return fmt.Sprintf("%s: %d", k, counts[k])
+	}), ", "))
+	for i, ie := range e.Errors {
+		if i >= maxReportedItemErrors {
+			fmt.Fprintf(&sb, "\n  ... and %d more", len(e.Errors)-maxReportedItemErrors)
+			break
+		}
+		fmt.Fprintf(&sb, "\n  %s", ie.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap returns the errors of the notifications.
+func (e *TriageError) Unwrap() []error {
+	return lo.Map(e.Errors, func(ie *ItemError, _ int) error {
+		return ie
+	})
+}
+
+// Counts returns the number of errors for each kind.
+func (e *TriageError) Counts() map[string]int {
+	counts := map[string]int{}
+	for _, ie := range e.Errors {
+		counts[errorKind(ie.Err)]++
+	}
+	return counts
+}
+
+// errorKind classifies the error such as "HTTP 502", "rate limit" or "timeout".
+func errorKind(err error) string {
+	var rateLimitErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	var errResp *github.ErrorResponse
+	var netErr net.Error
+	switch {
+	case errors.As(err, &rateLimitErr), errors.As(err, &abuseErr):
+		return "rate limit"
+	case errors.As(err, &errResp) && errResp.Response != nil:
+		return fmt.Sprintf("HTTP %d", errResp.Response.StatusCode)
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &netErr):
+		return "network"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,33 @@
+package gh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v71/github"
+)
+
+func TestTriageErrorError(t *testing.T) {
+	httpErr := func(status int) error {
+		return fmt.Errorf("failed to get issue: %w", &github.ErrorResponse{Response: &http.Response{StatusCode: status, Request: &http.Request{}}, Message: http.StatusText(status)})
+	}
+	e := &TriageError{
+		Total: 10,
+		Errors: []*ItemError{
+			{ThreadID: "1", Subject: "owner/repo Fix bug", Err: httpErr(http.StatusBadGateway)},
+			{ThreadID: "2", Subject: "owner/repo Add feature", Err: context.DeadlineExceeded},
+			{ThreadID: "3", Subject: "owner/repo Update docs", Err: httpErr(http.StatusBadGateway)},
+			{ThreadID: "4", Subject: "owner/repo Refactor", Err: errors.New("boom")},
+		},
+	}
+	want := "failed to process 4 of 10 notifications (HTTP 502: 2, other: 1, timeout: 1)"
+	if got := e.Error(); len(got) < len(want) || got[:len(want)] != want {
+		t.Errorf("got %q, want prefix %q", got, want)
+	}
+	if !errors.Is(e, context.DeadlineExceeded) {
+		t.Error("Expected to unwrap the errors of notifications")
+	}
+}
@@ -0,0 +1,96 @@
+package gh
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/k1LoW/gh-triage/profile"
+)
+
+// execEnvPrefix is the prefix of the environment variables passed to the command of the exec action.
+const execEnvPrefix = "GH_TRIAGE_"
+
+// execWaitDelay is the time to wait for the output of the command to be closed after it is killed.
+const execWaitDelay = time.Second
+
+// execCommand runs the command of the exec action in the background, and calls applied when the command succeeds.
+// The command receives the fields as JSON on stdin and as GH_TRIAGE_* environment variables.
+func (c *Client) execCommand(ctx context.Context, a *profile.RuleAction, m map[string]any, applied func()) error {
+	in, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal fields: %w", err)
+	}
+	timeout, err := a.ExecTimeout()
+	if err != nil {
+		return err
+	}
+	env := append(os.Environ(), execEnv(m)...)
+	r := ref(m)
+	sem := c.execSems[a]
+
+	c.execWG.Add(1)
+	go func() {
+		defer c.execWG.Done()
+		sem <- struct{}{}
+		defer func() { <-sem }()
+
+		// The command should finish even if processing of the page is finished, so only the timeout cancels it.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, "sh", "-c", a.Command) //nolint:gosec
+		cmd.Env = env
+		// Do not wait for the grandchildren holding stdout/stderr after the command is killed.
+		cmd.WaitDelay = execWaitDelay
+		cmd.Stdin = bytes.NewReader(in)
+		stdout := new(bytes.Buffer)
+		stderr := new(bytes.Buffer)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		if err := cmd.Run(); err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("timed out after %s: %w", timeout, err)
+			}
+			err = fmt.Errorf("failed to exec %q for %s: %w: %s", a.Command, r, err, strings.TrimSpace(stderr.String()))
+			if a.FailOnError {
+				c.execMu.Lock()
+				c.execErrs = append(c.execErrs, err)
+				c.execMu.Unlock()
+				return
+			}
+			slog.Error("Exec action failed", "command", a.Command, "subject", r, "error", err)
+			return
+		}
+		if c.verbose {
+			slog.Info("Exec action succeeded", "command", a.Command, "subject", r, "stdout", strings.TrimSpace(stdout.String()))
+		}
+		applied()
+	}()
+	return nil
+}
+
+// waitExecs waits for all running commands of the exec action and returns the errors of commands that fail on error.
+func (c *Client) waitExecs() error {
+	c.execWG.Wait()
+	c.execMu.Lock()
+	defer c.execMu.Unlock()
+	err := errors.Join(c.execErrs...)
+	c.execErrs = nil
+	return err
+}
+
+// execEnv returns the fields as GH_TRIAGE_* environment variables.
+func execEnv(m map[string]any) []string {
+	var env []string
+	for _, k := range fieldNames(m) {
+		env = append(env, execEnvPrefix+strings.ToUpper(k)+"="+formatCell(m[k]))
+	}
+	return env
+}
@@ -0,0 +1,192 @@
+package gh
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k1LoW/gh-triage/profile"
+	"github.com/k1LoW/gh-triage/state"
+)
+
+func TestExecCommand(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	a := &profile.RuleAction{
+		Type:    profile.ActionExec,
+		Command: `cat > ` + out + ` && echo "$GH_TRIAGE_OWNER/$GH_TRIAGE_REPO#$GH_TRIAGE_NUMBER $GH_TRIAGE_LABELS" >> ` + out,
+	}
+	c := &Client{
+		w:        new(bytes.Buffer),
+		execSems: map[*profile.RuleAction]chan struct{}{a: make(chan struct{}, 1)},
+	}
+	m := map[string]any{
+		"owner":  "k1LoW",
+		"repo":   "gh-triage",
+		"number": 1,
+		"labels": []string{"bug", "help wanted"},
+	}
+	applied := false
+	if err := c.execCommand(context.Background(), a, m, func() { applied = true }); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.waitExecs(); err != nil {
+		t.Fatal(err)
+	}
+	if !applied {
+		t.Error("Expected the action to be applied")
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"labels":["bug","help wanted"],"number":1,"owner":"k1LoW","repo":"gh-triage"}k1LoW/gh-triage#1 bug,help wanted` + "\n"
+	if got := string(b); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExecCommandFailOnError(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  *profile.RuleAction
+		wantErr string
+	}{
+		{"fail on error", &profile.RuleAction{Type: profile.ActionExec, Command: "echo oops >&2; exit 1", FailOnError: true}, "oops"},
+		{"timeout", &profile.RuleAction{Type: profile.ActionExec, Command: "sleep 10", Timeout: "100ms", FailOnError: true}, "timed out after 100ms"},
+		{"ignore error", &profile.RuleAction{Type: profile.ActionExec, Command: "exit 1"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{
+				w:        new(bytes.Buffer),
+				execSems: map[*profile.RuleAction]chan struct{}{tt.action: make(chan struct{}, 1)},
+			}
+			applied := false
+			if err := c.execCommand(context.Background(), tt.action, map[string]any{}, func() { applied = true }); err != nil {
+				t.Fatal(err)
+			}
+			err := c.waitExecs()
+			if applied {
+				t.Error("Expected the failed command not to be applied")
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTriageExecOnce(t *testing.T) {
+	notifications := `[
+  {"id": "1", "unread": true, "updated_at": "2025-01-01T00:00:00Z", "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "Fix bug", "url": "https://api.github.com/repos/owner/repo/issues/1", "type": "Issue"}}
+]`
+	updatedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		command     string
+		wantApplied bool
+	}{
+		{"succeeded", "true", true},
+		{"failed", "exit 1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newFakeGitHub(t, notifications, func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, `{"number":1,"state":"open"}`)
+			})
+			defer ts.Close()
+			st, err := state.Open(filepath.Join(t.TempDir(), "default.state.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rule := profile.Rule{Name: "hook", Max: 10, Conditions: []string{"*"}, Actions: []profile.RuleAction{{Type: profile.ActionExec, Command: tt.command, Once: true}}}
+			c := newTestClient(t, &profile.Profile{Rules: []profile.Rule{rule}}, ts, io.Discard, WithStore(st))
+			if err := c.Triage(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := st.Applied("1", actionKey(rule, 0, rule.Actions[0]), updatedAt); got != tt.wantApplied {
+				t.Errorf("got applied %v, want %v", got, tt.wantApplied)
+			}
+		})
+	}
+}
+
+func TestTriageWaitsExecsOnError(t *testing.T) {
+	notifications := `[
+  {"id": "1", "unread": true, "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "Fix bug", "url": "https://api.github.com/repos/owner/repo/issues/1", "type": "Issue"}},
+  {"id": "2", "unread": true, "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "Broken", "url": "https://api.github.com/repos/owner/repo/issues/2", "type": "Issue"}}
+]`
+	ts := newFakeGitHub(t, notifications, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/owner/repo/issues/2" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = io.WriteString(w, `{"number":1,"state":"open"}`)
+	})
+	defer ts.Close()
+	out := filepath.Join(t.TempDir(), "out")
+	cfg := &profile.Profile{
+		Rules:       []profile.Rule{{Max: 10, Conditions: []string{"number == 1"}, Actions: []profile.RuleAction{{Type: profile.ActionExec, Command: "sleep 0.2 && touch " + out}}}},
+		Concurrency: 1,
+	}
+	c := newTestClient(t, cfg, ts, io.Discard, WithFailFast(true))
+	if err := c.Triage(context.Background()); err == nil {
+		t.Fatal("Expected the error of the notification")
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("Expected the command to have finished when the triage returned: %v", err)
+	}
+}
+
+func TestTriageSavesStateOnExecError(t *testing.T) {
+	notifications := `[
+  {"id": "1", "unread": true, "updated_at": "2025-01-01T00:00:00Z", "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "Fix bug", "url": "https://api.github.com/repos/owner/repo/issues/1", "type": "Issue"}}
+]`
+	var comments int
+	ts := newFakeGitHub(t, notifications, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/repos/owner/repo/issues/1/comments" {
+			comments++
+			_, _ = io.WriteString(w, "{}")
+			return
+		}
+		_, _ = io.WriteString(w, `{"number":1,"state":"open"}`)
+	})
+	defer ts.Close()
+	p := filepath.Join(t.TempDir(), "default.state.json")
+	rule := profile.Rule{Name: "thank", Max: 10, Conditions: []string{"open"}, Actions: []profile.RuleAction{
+		{Type: profile.ActionComment, Body: "Thanks!", Once: true},
+		{Type: profile.ActionList},
+		{Type: profile.ActionExec, Command: "exit 1", FailOnError: true},
+	}}
+	cfg := &profile.Profile{Rules: []profile.Rule{rule}}
+	for i := range 2 {
+		st, err := state.Open(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := new(bytes.Buffer)
+		c := newTestClient(t, cfg, ts, out, WithStore(st), WithFormat(FormatJSON))
+		if err := c.Triage(context.Background()); err == nil {
+			t.Fatal("Expected the error of the command")
+		}
+		// The list is output even if the triage fails.
+		if !strings.Contains(out.String(), `"title": "Fix bug"`) {
+			t.Errorf("run %d: got %q", i, out.String())
+		}
+	}
+	if comments != 1 {
+		t.Errorf("got %d comments, want 1", comments)
+	}
+}
@@ -0,0 +1,141 @@
+package gh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/k1LoW/gh-triage/cond"
+	"github.com/k1LoW/gh-triage/profile"
+	"github.com/samber/lo"
+)
+
+var issueRefRe = regexp.MustCompile(`^([^/\s]+)/([^#\s]+)#(\d+)$`)
+
+// Explain prints the fields of the notification specified by target and
+// the result of each condition of each rule in the profile.
+// target is a notification thread ID or a reference such as owner/repo#number.
+func (c *Client) Explain(ctx context.Context, target string) error {
+	n, err := c.findNotification(ctx, target)
+	if err != nil {
+		return err
+	}
+	// Every field is shown, even if no condition refers to it.
+	c.needs = allNeeds
+	m, err := c.fields(ctx, n)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return fmt.Errorf("the subject of notification %s could not be resolved (type: %s)", n.GetID(), n.GetSubject().GetType())
+	}
+
+	if _, err := fmt.Fprintf(c.w, "%s (thread %s)\n  %s\n\n", numberC.Sprint(ref(m)), n.GetID(), titleC.Sprint(m["title"])); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(c.w, "Fields:"); err != nil {
+		return err
+	}
+	for _, k := range fieldNames(m) {
+		if _, err := fmt.Fprintf(c.w, "  %s: %s\n", k, formatValue(m[k])); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintln(c.w, "\nRules:"); err != nil {
+		return err
+	}
+	for _, r := range c.rules {
+		matched := false
+		var lines []string
+		for _, expr := range r.Conditions {
+			tf, err := cond.Eval(expr, m)
+			matched = matched || tf
+			line := fmt.Sprintf("    %s %s", resultMark(tf), expr)
+			if err != nil {
+				line += " " + failedC.Sprintf("(error: %v)", err)
+			}
+			lines = append(lines, line)
+		}
+		actions := strings.Join(lo.Map(r.Actions, func(a profile.RuleAction, _ int) string {
+			return a.Type
+		}), ", ")
+		if _, err := fmt.Fprintf(c.w, "  %s %s (actions: %s, max: %d, continue: %t)\n", resultMark(matched), r.DisplayName(), actions, r.Max, r.Continue); err != nil {
+			return err
+		}
+		for _, line := range lines {
+			if _, err := fmt.Fprintln(c.w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// findNotification finds the notification specified by a thread ID or owner/repo#number.
+func (c *Client) findNotification(ctx context.Context, target string) (*github.Notification, error) {
+	if _, err := strconv.ParseInt(target, 10, 64); err == nil {
+		n, _, err := c.client.Activity.GetThread(ctx, target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get notification thread: %w", err)
+		}
+		return n, nil
+	}
+	matches := issueRefRe.FindStringSubmatch(target)
+	if matches == nil {
+		return nil, fmt.Errorf("invalid target: %s (expected a thread ID or owner/repo#number)", target)
+	}
+	owner, repo, number := matches[1], matches[2], matches[3]
+	page := 1
+	for {
+		notifications, res, err := c.client.Activity.ListRepositoryNotifications(ctx, owner, repo, &github.NotificationListOptions{
+			All: true,
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repository notifications: %w", err)
+		}
+		for _, n := range notifications {
+			u, err := url.Parse(n.GetSubject().GetURL())
+			if err != nil {
+				continue
+			}
+			if path.Base(u.Path) == number {
+				return n, nil
+			}
+		}
+		if res.NextPage == 0 {
+			break
+		}
+		page = res.NextPage
+	}
+	return nil, errors.New("notification not found: " + target)
+}
+
+func resultMark(tf bool) string {
+	if tf {
+		return passedC.Sprint("✔")
+	}
+	return failedC.Sprint("✘")
+}
+
+func formatValue(v any) string {
+	switch vv := v.(type) {
+	case string:
+		return strconv.Quote(vv)
+	case time.Time:
+		return formatCell(vv)
+	default:
+		return fmt.Sprintf("%v", vv)
+	}
+}
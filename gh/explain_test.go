@@ -0,0 +1,113 @@
+package gh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/k1LoW/gh-triage/profile"
+)
+
+func TestExplain(t *testing.T) {
+	notification := func(id string, number int) string {
+		return fmt.Sprintf(`{"id": %q, "unread": true, "reason": "mention", "updated_at": "2025-01-02T00:00:00Z", "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "Issue %d", "url": "https://api.github.com/repos/owner/repo/issues/%d", "type": "Issue"}}`, id, number, number)
+	}
+	var pages []string
+	ts := newFakeGitHub(t, "[]", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/notifications":
+			page := r.URL.Query().Get("page")
+			pages = append(pages, page)
+			if r.URL.Query().Get("all") != "true" {
+				t.Error("Expected read notifications to be listed too")
+			}
+			switch page {
+			case "", "1":
+				w.Header().Set("Link", fmt.Sprintf(`<http://%s/repos/owner/repo/notifications?all=true&page=2&per_page=100>; rel="next"`, r.Host))
+				_, _ = io.WriteString(w, "["+notification("10", 1)+"]")
+			case "2":
+				_, _ = io.WriteString(w, "["+notification("20", 12)+"]")
+			default:
+				_, _ = io.WriteString(w, "[]")
+			}
+		case "/notifications/threads/10":
+			_, _ = io.WriteString(w, notification("10", 1))
+		case "/repos/owner/repo/issues/1":
+			_, _ = io.WriteString(w, `{"number":1,"state":"open","labels":[{"name":"bug"}]}`)
+		case "/repos/owner/repo/issues/12":
+			_, _ = io.WriteString(w, `{"number":12,"state":"closed","closed_at":"2025-01-03T00:00:00Z","labels":[]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"message":"Not Found"}`)
+		}
+	})
+	defer ts.Close()
+	cfg := &profile.Profile{
+		Rules: []profile.Rule{
+			{Name: "bugs", Max: 10, Conditions: []string{"is_pull_request", "'bug' in labels"}, Actions: []profile.RuleAction{{Type: profile.ActionList}}},
+			{Name: "closed", Max: 5, Conditions: []string{"closed", "undefined_func()"}, Actions: []profile.RuleAction{{Type: profile.ActionDone}, {Type: profile.ActionRead}}},
+		},
+	}
+
+	t.Run("reference", func(t *testing.T) {
+		pages = nil
+		out := new(bytes.Buffer)
+		c := newTestClient(t, cfg, ts, out)
+		if err := c.Explain(context.Background(), "owner/repo#12"); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := strings.Join(pages, ","), "1,2"; got != want {
+			t.Errorf("got pages %s, want %s", got, want)
+		}
+		for _, want := range []string{
+			"owner/repo #12 (thread 20)\n  Issue 12\n",
+			"\n  thread_id: \"20\"\n",
+			"\n  number: 12\n",
+			"\n  closed: true\n",
+			"\n  labels: []\n",
+			"\n  last_read_at: \n",
+			"\n  ✘ bugs (actions: list, max: 10, continue: false)\n    ✘ is_pull_request\n    ✘ 'bug' in labels\n",
+			"\n  ✔ closed (actions: done, read, max: 5, continue: false)\n    ✔ closed\n    ✘ undefined_func() (error: ",
+		} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("Expected %q in output, got\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("thread ID", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		c := newTestClient(t, cfg, ts, out)
+		if err := c.Explain(context.Background(), "10"); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{
+			"owner/repo #1 (thread 10)\n  Issue 1\n",
+			"\n  labels: [bug]\n",
+			"\n  ✔ bugs (actions: list, max: 10, continue: false)\n    ✘ is_pull_request\n    ✔ 'bug' in labels\n",
+		} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("Expected %q in output, got\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		c := newTestClient(t, cfg, ts, io.Discard)
+		for target, want := range map[string]string{
+			"owner/repo":    "invalid target: owner/repo",
+			"owner/repo#1x": "invalid target: owner/repo#1x",
+			"owner/repo#99": "notification not found: owner/repo#99",
+			"owner/other#1": "failed to list repository notifications",
+			"12345":         "failed to get notification thread",
+		} {
+			if err := c.Explain(context.Background(), target); err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: got error %v, want %s", target, err, want)
+			}
+		}
+	})
+}
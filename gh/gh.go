@@ -6,39 +6,74 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"text/template"
+	"time"
 
-	"github.com/expr-lang/expr"
 	"github.com/fatih/color"
 	"github.com/google/go-github/v71/github"
+	"github.com/k1LoW/gh-triage/cond"
+	"github.com/k1LoW/gh-triage/history"
+	"github.com/k1LoW/gh-triage/httpcache"
 	"github.com/k1LoW/gh-triage/profile"
+	"github.com/k1LoW/gh-triage/state"
 	"github.com/k1LoW/go-github-client/v71/factory"
 	"github.com/pkg/browser"
 	"github.com/samber/lo"
-	"github.com/savioxavier/termlink"
 	"github.com/shurcooL/githubv4"
 	"golang.org/x/sync/errgroup"
 )
 
 type Client struct {
-	config           *profile.Profile
-	client           *github.Client
-	v4Client         *githubv4.Client
-	w                io.Writer
-	verbose          bool
-	doneLimit        atomic.Int64 // Limit the number of issues/pull requests to mark as done
-	unsubscribeLimit atomic.Int64 // Limit the number of issues/pull requests to unsubscribe from
-	readLimit        atomic.Int64 // Limit the number of issues/pull requests to read
-	openLimit        atomic.Int64 // Limit the number of issues/pull requests to open
-	listLimit        atomic.Int64 // Limit the number of issues/pull requests to list
-	mu               sync.Mutex   // Mutex to protect concurrent access to limits
+	config        *profile.Profile
+	client        *github.Client
+	v4Client      *githubv4.Client
+	w             io.Writer
+	errW          io.Writer // Writer of the dry-run report when the list is output in the other formats than text
+	verbose       bool
+	dryRun        bool              // Evaluate actions without mutating notifications
+	format        string            // Output format of the list action
+	template      string            // Template of the list action
+	listers       map[string]lister // Listers of the list action keyed by template
+	rules         []profile.Rule
+	limits        []atomic.Int64                        // Limit the number of issues/pull requests to process for each rule
+	mu            sync.Mutex                            // Mutex to protect concurrent access to limits
+	execSems      map[*profile.RuleAction]chan struct{} // Semaphores to limit the concurrency of each exec action
+	execWG        sync.WaitGroup
+	execErrs      []error
+	execMu        sync.Mutex
+	webhookClient *http.Client
+	webhookSem    chan struct{} // Semaphore to limit the number of webhooks posted at the same time
+	webhookWG     sync.WaitGroup
+	actionTmpls   map[*profile.RuleAction]*template.Template // Templates of the webhook and notify actions
+	notifier      Notifier                                   // Backend of the notify action
+	store         *state.Store                               // Store recording the threads and the actions applied to them
+	history       *history.Log                               // Log of mutations performed
+	runID         string                                     // ID of the current run in the history
+	lastModified  map[string]string                          // Last-Modified of notifications of each source at the last triage
+	pollInterval  atomic.Int64                               // Poll interval requested by the server
+	batch         map[string]*batchSubject                   // Pull requests and issues of the current page fetched in batched GraphQL queries, keyed by the subject URL
+	needs         fieldNeeds                                 // Fields that need extra API calls and are used by the profile
+	timeDependent bool                                       // Whether conditions use time-based functions such as older_than, so that their results change without notifications changing
+	capped        bool                                       // Whether any rule reached max in the last evaluation, leaving matched notifications unprocessed
+	cache         *httpcache.Cache                           // On-disk cache of REST API responses
+	failFast      bool                                       // Stop processing at the first error of a notification
+	processed     atomic.Int64                               // Number of notifications processed in the current run
+	itemErrs      []*ItemError                               // Errors of notifications in the current run
+	itemErrsMu    sync.Mutex
+	notified      map[*profile.RuleAction]map[string]time.Time // Updated time of threads already announced by each notify action, kept across Triage calls
+	me            *identity                                    // Authenticated user, resolved once per Client
+	meMu          sync.Mutex                                   // Mutex to protect resolving the authenticated user
 }
 
 var (
@@ -52,8 +87,14 @@ var (
 	passedC     = color.RGB(31, 136, 61)
 	inProgressC = color.RGB(219, 171, 10)
 	failedC     = color.RGB(207, 34, 46)
+
+	dryRunC = color.RGB(219, 171, 10)
+	undoC   = color.RGB(31, 136, 61)
 )
 
+// checkSuiteTitleRe matches the title of CheckSuite and WorkflowRun notifications such as "CI workflow run failed for main branch".
+var checkSuiteTitleRe = regexp.MustCompile(`^(.+) workflow run (\S+) for (.+) branch$`)
+
 // discussionQuery is the GraphQL query for fetching a discussion.
 type discussionQuery struct {
 	Repository struct {
@@ -64,6 +105,8 @@ type discussionQuery struct {
 			Locked     bool
 			Number     int
 			IsAnswered bool
+			CreatedAt  time.Time
+			ClosedAt   *time.Time
 			Author     struct {
 				Login string
 			}
@@ -76,257 +119,671 @@ type discussionQuery struct {
 	} `graphql:"repository(owner: $owner, name: $repo)"`
 }
 
-func New(cfg *profile.Profile, w io.Writer, verbose bool) (*Client, error) {
+// Option is a functional option for Client.
+type Option func(*Client)
+
+// WithDryRun makes the client report which actions would be performed instead of performing them.
+func WithDryRun(dryRun bool) Option {
+	return func(c *Client) {
+		c.dryRun = dryRun
+	}
+}
+
+// WithFormat sets the output format of the list action.
+func WithFormat(format string) Option {
+	return func(c *Client) {
+		c.format = format
+	}
+}
+
+// WithTemplate sets the Go text/template of the list action. It takes precedence over the template in the profile.
+func WithTemplate(tmpl string) Option {
+	return func(c *Client) {
+		c.template = tmpl
+	}
+}
+
+// WithNotifier sets the backend of the notify action. Defaults to notify-send on Linux and osascript on macOS.
+func WithNotifier(n Notifier) Option {
+	return func(c *Client) {
+		c.notifier = n
+	}
+}
+
+// WithStore sets the store recording the threads and the actions applied to them, used by actions with once.
+func WithStore(s *state.Store) Option {
+	return func(c *Client) {
+		c.store = s
+	}
+}
+
+// WithHistory sets the log to record mutations performed.
+func WithHistory(h *history.Log) Option {
+	return func(c *Client) {
+		c.history = h
+	}
+}
+
+// WithCache sets the on-disk cache of REST API responses revalidated with ETag.
+func WithCache(cache *httpcache.Cache) Option {
+	return func(c *Client) {
+		c.cache = cache
+	}
+}
+
+// WithFailFast makes the client stop processing at the first error of a notification,
+// instead of processing the others and reporting the errors at the end.
+func WithFailFast(failFast bool) Option {
+	return func(c *Client) {
+		c.failFast = failFast
+	}
+}
+
+func New(cfg *profile.Profile, w io.Writer, verbose bool, opts ...Option) (*Client, error) {
 	client, err := factory.NewGithubClient()
 	if err != nil {
 		return nil, err
 	}
-	v4Client := githubv4.NewClient(client.Client())
+	client = withTransport(client, func(rt http.RoundTripper) http.RoundTripper {
+		return &rateLimitTransport{base: rt}
+	})
+	return newClient(cfg, client, w, verbose, opts...)
+}
 
-	return &Client{
+// newClient returns the client that uses the GitHub REST API client.
+func newClient(cfg *profile.Profile, client *github.Client, w io.Writer, verbose bool, opts ...Option) (*Client, error) {
+	c := &Client{
 		config:   cfg,
 		client:   client,
-		v4Client: v4Client,
+		v4Client: githubv4.NewClient(client.Client()),
 		w:        w,
+		errW:     os.Stderr,
 		verbose:  verbose,
-	}, nil
+		notifier: commandNotifier{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.cache != nil {
+		c.client = withTransport(c.client, c.cache.Transport)
+	}
+	c.rules = cfg.RuleSet()
+	c.limits = make([]atomic.Int64, len(c.rules))
+	c.listers = map[string]lister{}
+	if err := c.addLister(c.template); err != nil {
+		return nil, err
+	}
+	c.execSems = map[*profile.RuleAction]chan struct{}{}
+	c.webhookClient = &http.Client{Timeout: webhookTimeout}
+	c.webhookSem = make(chan struct{}, c.concurrency())
+	c.actionTmpls = map[*profile.RuleAction]*template.Template{}
+	c.notified = map[*profile.RuleAction]map[string]time.Time{}
+	c.lastModified = map[string]string{}
+	once := false
+	for i := range c.rules {
+		for j := range c.rules[i].Actions {
+			a := &c.rules[i].Actions[j]
+			once = once || a.Once
+			switch a.Type {
+			case profile.ActionList:
+				if a.Template != "" && c.listTemplate(*a) == "" {
+					slog.Warn("The list template of the profile is ignored for the format", "rule", c.rules[i].DisplayName(), "format", c.format)
+				}
+				if err := c.addLister(c.listTemplate(*a)); err != nil {
+					return nil, err
+				}
+			case profile.ActionExec:
+				c.execSems[a] = make(chan struct{}, max(a.Concurrency, 1))
+			case profile.ActionWebhook, profile.ActionNotify:
+				if err := c.addActionTemplate(a); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if !once {
+		// The store is used only by the actions with once, so the state file is not written for nothing.
+		c.store = nil
+	}
+	c.needs = c.resolveNeeds()
+	// An invalid condition never matches and is reported when it is evaluated.
+	c.timeDependent, _ = cond.TimeDependent(lo.FlatMap(c.rules, func(r profile.Rule, _ int) []string {
+		return r.Conditions
+	}))
+	return c, nil
+}
+
+func (c *Client) Triage(ctx context.Context) (err error) {
+	// Finish the triage even if it fails, so that nothing is left running,
+	// and the actions performed so far are neither performed again on the next run nor missing from the list.
+	skipped := false
+	defer func() {
+		if skipped {
+			return
+		}
+		if ferr := c.finish(); ferr != nil {
+			err = errors.Join(err, ferr)
+		}
+	}()
+	c.runID = history.NewRunID()
+	c.processed.Store(0)
+	c.itemErrs = nil
+	for i, r := range c.rules {
+		c.limits[i].Store(int64(r.Max))
+	}
+	q, err := c.notificationsQuery(time.Now())
+	if err != nil {
+		return err
+	}
+	lastModified, err := c.triageSources(ctx, q)
+	if err != nil {
+		return err
+	}
+	if len(lastModified) == 0 {
+		if !c.capped && !c.timeDependent {
+			if c.verbose {
+				slog.Info("No changes in notifications since the last triage, skipping")
+			}
+			skipped = true
+			return nil
+		}
+		// Notifications left by max, or matching time-based conditions only now, have to be evaluated even if nothing changed.
+		if c.verbose {
+			slog.Info("No changes in notifications since the last triage, evaluating them again as rules reached max or use time-based conditions")
+		}
+		clear(c.lastModified)
+		lastModified, err = c.triageSources(ctx, q)
+		if err != nil {
+			return err
+		}
+	}
+	c.capped = false
+	for i, r := range c.rules {
+		if r.Max > 0 && c.limits[i].Load() <= 0 {
+			c.capped = true
+		}
+	}
+
+	// Webhooks that fail are reported as errors of their notifications.
+	c.waitWebhooks()
+	if err := c.waitExecs(); err != nil {
+		return err
+	}
+	if len(c.itemErrs) > 0 {
+		return &TriageError{Total: int(c.processed.Load()), Errors: c.itemErrs}
+	}
+	// Remember Last-Modified only after all notifications are processed, so that a failed triage is retried in full.
+	for src, lm := range lastModified {
+		c.lastModified[src] = lm
+	}
+	return nil
+}
+
+// PollInterval returns the minimum interval between polls requested by the server with X-Poll-Interval, or 0 if unknown.
+func (c *Client) PollInterval() time.Duration {
+	return time.Duration(c.pollInterval.Load())
+}
+
+// finish waits for the exec and webhook actions running in the background, saves the state and flushes the list.
+// The actions running in the background have to finish before the state is saved, as they are recorded when they succeed.
+func (c *Client) finish() error {
+	c.waitWebhooks()
+	errs := []error{c.waitExecs()}
+	if c.store != nil && !c.dryRun {
+		if err := c.store.Save(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save state: %w", err))
+		}
+	}
+	templates := lo.Keys(c.listers)
+	slices.Sort(templates)
+	for _, tmpl := range templates {
+		errs = append(errs, c.listers[tmpl].flush())
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Client) action(ctx context.Context, n *github.Notification) error {
+	if c.exhausted() {
+		return nil // No more actions to perform
+	}
+	m, err := c.fields(ctx, n)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return nil // Skip notifications whose subject could not be resolved
+	}
+
+	threadID := n.GetID()
+	updatedAt := n.GetUpdatedAt().Time
+	record := c.store != nil && !c.dryRun
+	if record {
+		c.store.Seen(threadID, updatedAt)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stopped := false
+	for i := range c.rules {
+		r := &c.rules[i]
+		if stopped && !r.Always {
+			continue
+		}
+		if c.limits[i].Load() <= 0 {
+			continue
+		}
+		if !cond.Match(r.Conditions, m) {
+			continue
+		}
+		performed := false
+		for j := range r.Actions {
+			a := &r.Actions[j]
+			key := actionKey(*r, j, *a)
+			if a.Once && c.store != nil && c.store.Applied(threadID, key, updatedAt) {
+				if c.verbose {
+					slog.Info("Action already performed, skipping", "action", key, "subject", ref(m))
+				}
+				continue
+			}
+			e := c.historyEntry(*r, *a, m)
+			// Actions run in the background, such as exec, are recorded as applied only after they succeed.
+			applied := func() {
+				if record && a.Once {
+					c.store.Apply(threadID, key, updatedAt)
+				}
+			}
+			if err := c.perform(ctx, n, a, m, applied); err != nil {
+				if errors.Is(err, errSkipped) {
+					continue
+				}
+				return err
+			}
+			performed = true
+			if e != nil {
+				if err := c.history.Append(*e); err != nil {
+					return fmt.Errorf("failed to write history: %w", err)
+				}
+			}
+		}
+		// Items whose actions were all performed before do not count toward max.
+		if performed {
+			c.limits[i].Add(-1)
+		}
+		if !r.Continue {
+			stopped = true
+		}
+	}
+	return nil
 }
 
-func (c *Client) Triage(ctx context.Context) error {
-	c.doneLimit.Store(int64(c.config.Done.Max))
-	c.unsubscribeLimit.Store(int64(c.config.Unsubscribe.Max))
-	c.readLimit.Store(int64(c.config.Read.Max))
-	c.openLimit.Store(int64(c.config.Open.Max))
-	c.listLimit.Store(int64(c.config.List.Max))
+// perform performs the action of a rule on the notification.
+// applied is called when the action has succeeded, which is after perform returns for the exec and webhook actions running in the background.
+func (c *Client) perform(ctx context.Context, n *github.Notification, a *profile.RuleAction, m map[string]any, applied func()) error {
+	if !c.dryRun {
+		switch a.Type {
+		case profile.ActionExec:
+			return c.execCommand(ctx, a, m, applied)
+		case profile.ActionWebhook:
+			return c.queueWebhook(ctx, n, a, m, applied)
+		}
+	}
+	if err := c.performSync(ctx, n, a, m); err != nil {
+		return err
+	}
+	applied()
+	return nil
+}
+
+// performSync performs the action that finishes before it returns.
+func (c *Client) performSync(ctx context.Context, n *github.Notification, a *profile.RuleAction, m map[string]any) error {
+	switch a.Type {
+	case profile.ActionList:
+		return c.listers[c.listTemplate(*a)].add(m)
+	case profile.ActionLabel, profile.ActionUnlabel, profile.ActionComment, profile.ActionAssign, profile.ActionRequestReview:
+		if c.dryRun {
+			return c.reportDryRun(a.Type, m)
+		}
+		return c.editSubject(ctx, *a, m)
+	case profile.ActionExec, profile.ActionWebhook:
+		return c.reportDryRun(a.Type, m)
+	case profile.ActionNotify:
+		if c.dryRun {
+			return c.reportDryRun(a.Type, m)
+		}
+		return c.notify(ctx, a, m)
+	case profile.ActionOpen, profile.ActionDone, profile.ActionUnsubscribe, profile.ActionRead:
+	default:
+		return fmt.Errorf("unsupported action type: %s", a.Type)
+	}
+	if c.dryRun {
+		if err := c.reportDryRun(a.Type, m); err != nil {
+			return err
+		}
+		m["unread"] = false
+		return nil
+	}
+	switch a.Type {
+	case profile.ActionOpen:
+		htmlURL, _ := m["html_url"].(string)
+		if err := browser.OpenURL(htmlURL); err != nil {
+			return fmt.Errorf("failed to open URL in browser: %w", err)
+		}
+	case profile.ActionDone:
+		id, err := strconv.ParseInt(n.GetID(), 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse notification ID: %w", err)
+		}
+		if _, err := c.client.Activity.MarkThreadDone(ctx, id); err != nil {
+			return fmt.Errorf("failed to mark notification as done: %w", err)
+		}
+	case profile.ActionUnsubscribe:
+		if _, err := c.client.Activity.DeleteThreadSubscription(ctx, n.GetID()); err != nil {
+			return fmt.Errorf("failed to unsubscribe from notification: %w", err)
+		}
+	case profile.ActionRead:
+		if _, err := c.client.Activity.MarkThreadRead(ctx, n.GetID()); err != nil {
+			return fmt.Errorf("failed to mark notification as read: %w", err)
+		}
+	}
+	m["unread"] = false // Mark as read if opened, done, unsubscribed or read
+	return nil
+}
+
+// triageSources processes the notifications of all sources.
+// It returns Last-Modified of the sources that changed since the last triage.
+func (c *Client) triageSources(ctx context.Context, q url.Values) (map[string]string, error) {
+	lastModified := map[string]string{}
+	for _, src := range c.sources() {
+		lm, changed, err := c.triageSource(ctx, src, q)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			lastModified[src] = lm
+		}
+	}
+	return lastModified, nil
+}
+
+// triageSource processes the notifications listed from the source.
+// It returns Last-Modified of the source, and whether anything changed since the last triage.
+func (c *Client) triageSource(ctx context.Context, src string, q url.Values) (string, bool, error) {
+	var lastModified string
 	page := 1
 	for {
-		notifications, _, err := c.client.Activity.ListNotifications(ctx, &github.NotificationListOptions{
-			ListOptions: github.ListOptions{
-				Page:    page,
-				PerPage: 100,
-			},
-		})
+		notifications, res, err := c.listNotifications(ctx, src, q, page)
 		if err != nil {
-			return err
+			return "", false, err
+		}
+		if page == 1 {
+			if res.StatusCode == http.StatusNotModified {
+				if c.verbose {
+					slog.Info("No changes in notifications since the last triage, skipping", "source", src, "last_modified", c.lastModified[src])
+				}
+				return "", false, nil
+			}
+			lastModified = res.Header.Get("Last-Modified")
 		}
 		if len(notifications) == 0 {
 			break
 		}
+		if err := c.waitRateLimit(ctx, len(notifications)*callsPerNotification); err != nil {
+			return "", false, err
+		}
+		notifications = lo.Filter(notifications, func(n *github.Notification, _ int) bool {
+			return c.inScope(n)
+		})
+		c.batch = c.prefetch(ctx, notifications)
 		eg, ctx := errgroup.WithContext(ctx)
+		eg.SetLimit(c.concurrency())
 		for _, n := range notifications {
 			eg.Go(func() error {
-				return c.action(ctx, n)
+				c.processed.Add(1)
+				err := c.action(ctx, n)
+				if err == nil || c.failFast || ctx.Err() != nil {
+					return err
+				}
+				c.addItemError(n, err)
+				return nil
 			})
 		}
-		if err := eg.Wait(); err != nil {
-			return fmt.Errorf("failed to process notifications: %w", err)
+		err = eg.Wait()
+		c.batch = nil
+		if err != nil {
+			return "", false, fmt.Errorf("failed to process notifications: %w", err)
 		}
 		page++
 	}
+	return lastModified, true, nil
+}
+
+// addItemError records the error of the notification, so that the other notifications are processed.
+func (c *Client) addItemError(n *github.Notification, err error) {
+	c.itemErrsMu.Lock()
+	defer c.itemErrsMu.Unlock()
+	c.itemErrs = append(c.itemErrs, &ItemError{
+		ThreadID: n.GetID(),
+		Subject:  n.GetRepository().GetFullName() + " " + n.GetSubject().GetTitle(),
+		Err:      err,
+	})
+}
+
+// concurrency returns the number of notifications processed at the same time.
+func (c *Client) concurrency() int {
+	if c.config.Concurrency > 0 {
+		return c.config.Concurrency
+	}
+	return defaultConcurrency
+}
 
+// sources returns the API paths to list notifications from.
+// Notifications are listed per repository if the profile is scoped to repositories only, and globally otherwise.
+func (c *Client) sources() []string {
+	if len(c.config.Repositories) == 0 || len(c.config.Owners) > 0 {
+		return []string{"notifications"}
+	}
+	return lo.Map(c.config.Repositories, func(r string, _ int) string {
+		return fmt.Sprintf("repos/%s/notifications", r)
+	})
+}
+
+// inScope reports whether the notification belongs to the repositories or owners the profile is scoped to.
+func (c *Client) inScope(n *github.Notification) bool {
+	if len(c.config.Repositories) == 0 && len(c.config.Owners) == 0 {
+		return true
+	}
+	owner := n.GetRepository().GetOwner().GetLogin()
+	fullName := owner + "/" + n.GetRepository().GetName()
+	return slices.ContainsFunc(c.config.Repositories, func(r string) bool {
+		return strings.EqualFold(r, fullName)
+	}) || slices.ContainsFunc(c.config.Owners, func(o string) bool {
+		return strings.EqualFold(o, owner)
+	})
+}
+
+// notificationsQuery returns the query to list notifications filtered by the profile.
+// Relative times such as 7d are resolved once, so that all pages are listed with the same filters.
+func (c *Client) notificationsQuery(now time.Time) (url.Values, error) {
+	q := url.Values{}
+	q.Set("per_page", "100")
+	if c.config.All {
+		q.Set("all", "true")
+	}
+	if c.config.Participating {
+		q.Set("participating", "true")
+	}
+	since, err := c.config.SinceTime(now)
+	if err != nil {
+		return nil, err
+	}
+	if !since.IsZero() {
+		q.Set("since", since.Format(time.RFC3339))
+	}
+	before, err := c.config.BeforeTime(now)
+	if err != nil {
+		return nil, err
+	}
+	if !before.IsZero() {
+		q.Set("before", before.Format(time.RFC3339))
+	}
+	return q, nil
+}
+
+// listNotifications lists a page of notifications from the source filtered by the query.
+// The first page is requested with If-Modified-Since of the last triage, and the response has status 304 if nothing changed.
+// Conditional requests that return 304 do not count against the rate limit.
+func (c *Client) listNotifications(ctx context.Context, src string, q url.Values, page int) ([]*github.Notification, *github.Response, error) {
+	q = maps.Clone(q)
+	q.Set("page", strconv.Itoa(page))
+	req, err := c.client.NewRequest(http.MethodGet, src+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if lm := c.lastModified[src]; page == 1 && lm != "" {
+		req.Header.Set("If-Modified-Since", lm)
+	}
+	var notifications []*github.Notification
+	res, err := c.client.Do(ctx, req, &notifications)
+	if res != nil {
+		if v, err := strconv.Atoi(res.Header.Get("X-Poll-Interval")); err == nil && v > 0 {
+			c.pollInterval.Store(int64(time.Duration(v) * time.Second))
+		}
+	}
+	if err != nil {
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotModified {
+			return nil, res, nil
+		}
+		return nil, nil, err
+	}
+	return notifications, res, nil
+}
+
+// exhausted reports whether all rules have reached their limits.
+func (c *Client) exhausted() bool {
+	for i := range c.limits {
+		if c.limits[i].Load() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// listTemplate returns the template of the list action. The --template option takes precedence,
+// and the template in the profile is not used with the other formats than text given by the --format option.
+func (c *Client) listTemplate(a profile.RuleAction) string {
+	if c.template != "" {
+		return c.template
+	}
+	if c.format != "" && c.format != FormatText {
+		return ""
+	}
+	return a.Template
+}
+
+func (c *Client) addLister(tmpl string) error {
+	if _, ok := c.listers[tmpl]; ok {
+		return nil
+	}
+	l, err := newLister(c.format, tmpl, c.w)
+	if err != nil {
+		return err
+	}
+	c.listers[tmpl] = l
 	return nil
 }
 
-func (c *Client) action(ctx context.Context, n *github.Notification) error {
-	if c.readLimit.Load() <= 0 && c.openLimit.Load() <= 0 && c.listLimit.Load() <= 0 {
-		return nil // No more actions to perform
+// actionKey returns the key of the action of the rule in the store.
+// Rules with actions with once are named, so that the key is kept when the rules are reordered.
+func actionKey(r profile.Rule, j int, a profile.RuleAction) string {
+	return r.Name + ":" + strconv.Itoa(j) + ":" + a.Type
+}
+
+// addActionTemplate parses the template of the webhook and notify actions.
+func (c *Client) addActionTemplate(a *profile.RuleAction) error {
+	if a.Template == "" {
+		return nil
 	}
-	m := map[string]any{}
+	t, err := template.New(a.Type).Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := marshalJSON(v)
+			return string(b), err
+		},
+	}).Parse(a.Template)
+	if err != nil {
+		return fmt.Errorf("invalid %s template: %w", a.Type, err)
+	}
+	c.actionTmpls[a] = t
+	return nil
+}
+
+// fields collects the fields of the notification used for condition evaluation.
+// It returns nil if the subject of the notification should be skipped.
+func (c *Client) fields(ctx context.Context, n *github.Notification) (map[string]any, error) {
+	m := cond.NewEnv()
 	title := n.GetSubject().GetTitle()
 	u, err := url.Parse(n.GetSubject().GetURL())
 	if err != nil {
-		return fmt.Errorf("failed to parse URL: %w", err)
+		return nil, fmt.Errorf("failed to parse URL: %w", err)
 	}
 	owner := n.GetRepository().GetOwner().GetLogin()
 	repo := n.GetRepository().GetName()
+	repoHTMLURL := n.GetRepository().GetHTMLURL()
 	m["title"] = title
 	m["owner"] = owner
 	m["repo"] = repo
+	m["thread_id"] = n.GetID()
+	m["reason"] = n.GetReason()
+	m["subject_type"] = n.GetSubject().GetType()
+	m["updated_at"] = n.GetUpdatedAt().Time
+	m["last_read_at"] = n.GetLastReadAt().Time
+	m["unread"] = n.GetUnread()
 
-	me, _, err := c.client.Users.Get(ctx, "")
+	me, err := c.identity(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get authenticated user: %w", err)
+		return nil, err
 	}
-	m["me"] = me.GetLogin()
+	m["me"] = me.login
+	m["my_teams"] = me.teams
+	m["my_orgs"] = me.orgs
 
 	subjectType := n.GetSubject().GetType()
-	var htmlURL string
 	var number int
-	var isMerged bool
-
-	// Initialize default values
-	m["unread"] = true
-	m["is_issue"] = false
-	m["is_pull_request"] = false
-	m["is_discussion"] = false
-	m["is_release"] = false
-	m["number"] = -1
-	m["approved"] = false
-	m["answered"] = false
-	m["review_states"] = []string{}
-	m["state"] = "unknown"
-	m["draft"] = false
-	m["merged"] = false
-	m["mergeable"] = false
-	m["mergeable_state"] = "unknown"
-	m["closed"] = false
-	m["labels"] = []string{}
-	m["reviewers"] = []string{}
-	m["review_teams"] = []string{}
-	m["assignees"] = []string{}
-	m["author"] = ""
-	m["html_url"] = ""
-	m["status_passed"] = false
-	m["checks_passed"] = false
-	m["passed"] = false
-	m["failed"] = false
-	m["in_progress"] = false
 
 	switch subjectType {
 	case "Issue":
 		m["is_issue"] = true
 		number, err = strconv.Atoi(path.Base(u.Path))
 		if err != nil {
-			return fmt.Errorf("failed to parse number from URL: %w", err)
+			return nil, fmt.Errorf("failed to parse number from URL: %w", err)
 		}
 		m["number"] = number
-		issue, _, err := c.client.Issues.Get(ctx, owner, repo, number)
-		if err != nil {
-			var errResp *github.ErrorResponse
-			if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound {
-				if c.verbose {
-					slog.Warn("Issue not found, skipping", "owner", owner, "repo", repo, "number", number)
-				}
-				return nil
-			}
-			return fmt.Errorf("failed to get issue: %w", err)
-		}
-		htmlURL = issue.GetHTMLURL()
-		m["state"] = issue.GetState()
-		m["open"] = issue.GetState() == "open"
-		m["closed"] = !issue.GetClosedAt().Equal(github.Timestamp{})
-		m["labels"] = lo.Map(issue.Labels, func(l *github.Label, _ int) string {
-			return l.GetName()
-		})
-		m["assignees"] = lo.Map(issue.Assignees, func(a *github.User, _ int) string {
-			return a.GetLogin()
-		})
-		m["author"] = issue.GetUser().GetLogin()
-		m["html_url"] = issue.GetHTMLURL()
+		if b, ok := c.batch[n.GetSubject().GetURL()]; ok && b.issue != nil {
+			b.issue.apply(m)
+			break
+		}
+		if ok, err := c.issueFields(ctx, m, owner, repo, number); err != nil || !ok {
+			return nil, err
+		}
 	case "PullRequest":
 		m["is_pull_request"] = true
 		number, err = strconv.Atoi(path.Base(u.Path))
 		if err != nil {
-			return fmt.Errorf("failed to parse number from URL: %w", err)
+			return nil, fmt.Errorf("failed to parse number from URL: %w", err)
 		}
 		m["number"] = number
-		pr, _, err := c.client.PullRequests.Get(ctx, owner, repo, number)
-		if err != nil {
-			var errResp *github.ErrorResponse
-			if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound {
-				if c.verbose {
-					slog.Warn("Pull request not found, skipping", "owner", owner, "repo", repo, "number", number)
-				}
-				return nil
-			}
-			return fmt.Errorf("failed to get pull request: %w", err)
-		}
-		htmlURL = pr.GetHTMLURL()
-		m["state"] = pr.GetState()
-		m["open"] = pr.GetState() == "open"
-		m["draft"] = pr.GetDraft()
-		m["merged"] = pr.GetMerged()
-		isMerged = pr.GetMerged()
-		m["mergeable"] = pr.GetMergeable()
-		m["mergeable_state"] = pr.GetMergeableState()
-		m["closed"] = !pr.GetClosedAt().Equal(github.Timestamp{})
-		m["labels"] = lo.Map(pr.Labels, func(l *github.Label, _ int) string {
-			return l.GetName()
-		})
-		m["reviewers"] = lo.Map(pr.RequestedReviewers, func(r *github.User, _ int) string {
-			return r.GetLogin()
-		})
-		m["review_teams"] = lo.Map(pr.RequestedTeams, func(t *github.Team, _ int) string {
-			return t.GetName()
-		})
-		m["assignees"] = lo.Map(pr.Assignees, func(a *github.User, _ int) string {
-			return a.GetLogin()
-		})
-		m["author"] = pr.GetUser().GetLogin()
-		m["html_url"] = pr.GetHTMLURL()
-		reviews, _, err := c.client.PullRequests.ListReviews(ctx, owner, repo, number, &github.ListOptions{})
-		if err != nil {
-			return fmt.Errorf("failed to list pull request reviews: %w", err)
+		if b, ok := c.batch[n.GetSubject().GetURL()]; ok && b.pullRequest != nil {
+			b.pullRequest.apply(m, owner, c.needs)
+			break
 		}
-		slices.SortFunc(reviews, func(a, b *github.PullRequestReview) int {
-			return a.GetSubmittedAt().Compare(b.GetSubmittedAt().Time)
-		})
-		m["approved"] = false
-		var reviewStates []string
-		for _, review := range reviews {
-			state := review.GetState()
-			reviewStates = append(reviewStates, state)
-			if state == "APPROVED" {
-				m["approved"] = true
-				break
-			}
+		if ok, err := c.pullRequestFields(ctx, m, owner, repo, number); err != nil || !ok {
+			return nil, err
 		}
-		m["review_states"] = reviewStates
-		commitSHA := pr.GetHead().GetSHA()
-
-		combinedStatus, _, err := c.client.Repositories.GetCombinedStatus(ctx, owner, repo, commitSHA, &github.ListOptions{})
-		if err != nil {
-			return fmt.Errorf("failed to get combined status: %w", err)
-		}
-		statusPassed := true
-		statusFailed := false
-		statusInProgress := false
-	L:
-		for _, status := range combinedStatus.Statuses {
-			switch status.GetState() {
-			case "success":
-				continue
-			case "failure", "starup_failure":
-				statusPassed = false
-				statusFailed = true
-				break L
-			default:
-				statusPassed = false
-				statusInProgress = true
-			}
-		}
-		checkRuns, _, err := c.client.Checks.ListCheckRunsForRef(ctx, owner, repo, commitSHA, &github.ListCheckRunsOptions{})
-		if err != nil {
-			return fmt.Errorf("failed to list check runs: %w", err)
-		}
-		checksPassed := true
-		checksFailed := false
-		checksInProgress := false
-	LL:
-		for _, checkRun := range checkRuns.CheckRuns {
-			switch {
-			case checkRun.GetStatus() == "completed" && slices.Contains([]string{"neutral", "skipped", "success"}, checkRun.GetConclusion()):
-				continue
-			case slices.Contains([]string{"failure", "startup_failure"}, checkRun.GetStatus()) || slices.Contains([]string{"canceled", "failure", "stale", "timed_out"}, checkRun.GetConclusion()):
-				checksPassed = false
-				checksFailed = true
-				checksInProgress = false
-				break LL
-			case slices.Contains([]string{"expected", "in_progress", "pending", "queued", "requested", "waiting"}, checkRun.GetStatus()):
-				checksPassed = false
-				checksInProgress = true
-			}
-		}
-		m["status_passed"] = statusPassed
-		m["checks_passed"] = checksPassed
-		m["passed"] = statusPassed && checksPassed
-		m["failed"] = statusFailed || checksFailed
-		m["in_progress"] = statusInProgress || checksInProgress
 	case "Release":
 		m["is_release"] = true
 		id, err := strconv.Atoi(path.Base(u.Path))
 		if err != nil {
-			return fmt.Errorf("failed to parse release ID from URL: %w", err)
+			return nil, fmt.Errorf("failed to parse release ID from URL: %w", err)
 		}
 		r, _, err := c.client.Repositories.GetRelease(ctx, owner, repo, int64(id))
 		if err != nil {
@@ -335,17 +792,24 @@ func (c *Client) action(ctx context.Context, n *github.Notification) error {
 				if c.verbose {
 					slog.Warn("Release not found, skipping", "owner", owner, "repo", repo, "id", id)
 				}
-				return nil
+				return nil, nil
 			}
-			return fmt.Errorf("failed to get release: %w", err)
+			return nil, fmt.Errorf("failed to get release: %w", err)
 		}
-		htmlURL = r.GetHTMLURL()
 		m["html_url"] = r.GetHTMLURL()
+		m["release_id"] = id
+		m["tag_name"] = r.GetTagName()
+		m["target_commitish"] = r.GetTargetCommitish()
+		m["prerelease"] = r.GetPrerelease()
+		m["draft"] = r.GetDraft()
+		m["author"] = r.GetAuthor().GetLogin()
+		m["created_at"] = r.GetCreatedAt().Time
+		m["published_at"] = r.GetPublishedAt().Time
 	case "Discussion":
 		m["is_discussion"] = true
 		number, err = strconv.Atoi(path.Base(u.Path))
 		if err != nil {
-			return fmt.Errorf("failed to parse discussion number from URL: %w", err)
+			return nil, fmt.Errorf("failed to parse discussion number from URL: %w", err)
 		}
 		m["number"] = number
 		var q discussionQuery
@@ -358,144 +822,90 @@ func (c *Client) action(ctx context.Context, n *github.Notification) error {
 			if c.verbose {
 				slog.Warn("Discussion not found or error fetching, skipping", "owner", owner, "repo", repo, "number", number, "error", err)
 			}
-			return nil
+			return nil, nil
 		}
 		discussion := q.Repository.Discussion
-		htmlURL = discussion.URL
 		m["state"] = lo.Ternary(discussion.Closed, "closed", "open")
 		m["open"] = !discussion.Closed
 		m["closed"] = discussion.Closed
 		m["answered"] = discussion.IsAnswered
+		m["created_at"] = discussion.CreatedAt
+		if discussion.ClosedAt != nil {
+			m["closed_at"] = *discussion.ClosedAt
+		}
 		m["labels"] = lo.Map(discussion.Labels.Nodes, func(l struct{ Name string }, _ int) string {
 			return l.Name
 		})
 		m["author"] = discussion.Author.Login
 		m["html_url"] = discussion.URL
-	default:
-		slog.Warn("Unknown subject type", "type", subjectType, "url", n.GetSubject().GetURL())
-		return nil // Skip unknown subject types
-	}
-
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	open := false
-	if c.openLimit.Load() > 0 {
-		open = evalCond(c.config.Open.Conditions, m)
-		if open {
-			if err := browser.OpenURL(htmlURL); err != nil {
-				return fmt.Errorf("failed to open URL in browser: %w", err)
-			}
-			c.openLimit.Add(-1)
-			m["unread"] = false // Mark as read if opened
-		}
-	}
-	if !open {
-		done := false
-		if c.doneLimit.Load() > 0 {
-			done = evalCond(c.config.Done.Conditions, m)
-			if done {
-				id, err := strconv.ParseInt(n.GetID(), 10, 64)
-				if err != nil {
-					return fmt.Errorf("failed to parse notification ID: %w", err)
-				}
-				if _, err := c.client.Activity.MarkThreadDone(ctx, id); err != nil {
-					return fmt.Errorf("failed to mark notification as done: %w", err)
-				}
-				c.doneLimit.Add(-1)
-				m["unread"] = false // Mark as read if done
-			}
+	case "CheckSuite", "WorkflowRun":
+		if subjectType == "CheckSuite" {
+			m["is_check_suite"] = true
+		} else {
+			m["is_workflow_run"] = true
 		}
-		if !done {
-			unsubscribe := false
-			if c.unsubscribeLimit.Load() > 0 {
-				unsubscribe = evalCond(c.config.Unsubscribe.Conditions, m)
-				if unsubscribe {
-					if _, err := c.client.Activity.DeleteThreadSubscription(ctx, n.GetID()); err != nil {
-						return fmt.Errorf("failed to unsubscribe from notification: %w", err)
-					}
-					c.unsubscribeLimit.Add(-1)
-					m["unread"] = false // Mark as read if unsubscribed
-				}
-			}
-			if !unsubscribe {
-				if c.readLimit.Load() > 0 {
-					read := evalCond(c.config.Read.Conditions, m)
-					if read {
-						if _, err := c.client.Activity.MarkThreadRead(ctx, n.GetID()); err != nil {
-							return fmt.Errorf("failed to mark notification as read: %w", err)
-						}
-						c.readLimit.Add(-1)
-						m["unread"] = false // Mark as read if conditions are met
-					}
-				}
+		// The subjects of CheckSuite and WorkflowRun notifications have no URL, so the details are parsed from the title.
+		if matches := checkSuiteTitleRe.FindStringSubmatch(title); matches != nil {
+			m["workflow"] = matches[1]
+			m["conclusion"] = matches[2]
+			m["branch"] = matches[3]
+			switch matches[2] {
+			case "succeeded":
+				m["passed"] = true
+			case "failed":
+				m["failed"] = true
 			}
 		}
-	}
-	if c.listLimit.Load() > 0 {
-		list := evalCond(c.config.List.Conditions, m)
-		if list {
-			mark := "▬"
-			switch {
-			case m["state"] == "open":
-				if draft, ok := m["draft"].(bool); ok && draft {
-					mark = draftC.Sprint(mark)
-				} else {
-					mark = openC.Sprint(mark)
-				}
-			case isMerged:
-				mark = mergedC.Sprint(mark)
-			case m["state"] == "closed":
-				mark = closedC.Sprint(mark)
-			}
-			statusMark := "●"
-			if passed, ok := m["passed"].(bool); ok && passed {
-				statusMark = passedC.Sprint(statusMark)
-			} else if inProgress, ok := m["in_progress"].(bool); ok && inProgress {
-				statusMark = inProgressC.Sprint(statusMark)
-			} else if failed, ok := m["failed"].(bool); ok && failed {
-				statusMark = failedC.Sprint(statusMark)
-			} else {
-				statusMark = ""
-			}
-			number := mark + numberC.Sprintf(" %s/%s #%d", owner, repo, number) + " " + statusMark
-			if _, err := fmt.Fprintf(c.w, "%s\n", number); err != nil {
-				return err
-			}
-			if termlink.SupportsHyperlinks() {
-				if _, err := fmt.Fprintf(c.w, "  %s\n", termlink.Link(titleC.Sprint(title), htmlURL)); err != nil {
-					return err
-				}
-			} else {
-				if _, err := fmt.Fprintf(c.w, "  %s ( %s )\n", titleC.Sprint(title), htmlURL); err != nil {
-					return err
+		m["html_url"] = repoHTMLURL + "/actions"
+	case "Commit":
+		m["is_commit"] = true
+		sha := path.Base(u.Path)
+		m["sha"] = sha
+		commit, _, err := c.client.Repositories.GetCommit(ctx, owner, repo, sha, &github.ListOptions{})
+		if err != nil {
+			var errResp *github.ErrorResponse
+			if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound {
+				if c.verbose {
+					slog.Warn("Commit not found, skipping", "owner", owner, "repo", repo, "sha", sha)
 				}
+				return nil, nil
 			}
-			c.listLimit.Add(-1)
+			return nil, fmt.Errorf("failed to get commit: %w", err)
 		}
+		m["author"] = commit.GetAuthor().GetLogin()
+		m["created_at"] = commit.GetCommit().GetAuthor().GetDate().Time
+		m["html_url"] = commit.GetHTMLURL()
+	case "RepositoryVulnerabilityAlert":
+		m["is_vulnerability_alert"] = true
+		m["html_url"] = repoHTMLURL + "/security/dependabot"
+	case "RepositoryDependabotAlertsThread":
+		m["is_dependabot_alert"] = true
+		m["html_url"] = repoHTMLURL + "/security/dependabot"
+	case "SecurityAdvisory":
+		m["is_security_advisory"] = true
+		m["html_url"] = repoHTMLURL + "/security/advisories"
+		if id := path.Base(u.Path); strings.HasPrefix(id, "GHSA-") {
+			m["ghsa_id"] = id
+			m["html_url"] = repoHTMLURL + "/security/advisories/" + id
+		}
+	case "RepositoryInvitation":
+		m["is_repository_invitation"] = true
+		m["html_url"] = repoHTMLURL + "/invitations"
+	default:
+		slog.Warn("Unknown subject type", "type", subjectType, "url", n.GetSubject().GetURL())
+		return nil, nil // Skip unknown subject types
 	}
-	return nil
+
+	return m, nil
 }
 
-func evalCond(cond []string, m map[string]any) bool {
-	if len(cond) == 0 {
-		return false
-	}
-	joined := "(" + strings.Join(lo.Map(cond, func(cond string, _ int) string {
-		if cond == "*" {
-			return "true"
-		}
-		return cond
-	}), ") || (") + ")"
-	v, err := expr.Eval(joined, m)
-	if err != nil {
-		slog.Error("Failed to evaluate read condition", "cond", joined, "error", err)
-		return false
-	}
-	switch tf := v.(type) {
-	case bool:
-		return tf
-	default:
-		slog.Error("Condition did not evaluate to boolean", "cond", joined, "value", tf, "type", fmt.Sprintf("%T", tf))
-		return false
+// reportDryRun writes the action that would have been performed on the notification.
+// It is written to stderr with the other formats than text, so as not to break the output of the list action.
+func (c *Client) reportDryRun(action string, m map[string]any) error {
+	w := c.w
+	if c.format != "" && c.format != FormatText {
+		w = c.errW
 	}
+	_, err := fmt.Fprintf(w, "%s %-14s %s\n  %s ( %s )\n", dryRunC.Sprint("[dry-run]"), action, numberC.Sprint(ref(m)), titleC.Sprint(m["title"]), m["html_url"])
+	return err
 }
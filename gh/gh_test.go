@@ -0,0 +1,635 @@
+package gh
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/k1LoW/gh-triage/profile"
+	"github.com/k1LoW/gh-triage/state"
+	"github.com/shurcooL/githubv4"
+)
+
+func TestCheckSuiteTitleRe(t *testing.T) {
+	tests := []struct {
+		title      string
+		workflow   string
+		conclusion string
+		branch     string
+	}{
+		{"CI workflow run failed for main branch", "CI", "failed", "main"},
+		{"Release to production workflow run succeeded for release/v1 branch", "Release to production", "succeeded", "release/v1"},
+		{"Lint workflow run cancelled for feature branch branch", "Lint", "cancelled", "feature branch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			matches := checkSuiteTitleRe.FindStringSubmatch(tt.title)
+			if matches == nil {
+				t.Fatalf("no match: %s", tt.title)
+			}
+			if matches[1] != tt.workflow || matches[2] != tt.conclusion || matches[3] != tt.branch {
+				t.Errorf("got (%q, %q, %q), want (%q, %q, %q)", matches[1], matches[2], matches[3], tt.workflow, tt.conclusion, tt.branch)
+			}
+		})
+	}
+	if checkSuiteTitleRe.MatchString("Fix bug") {
+		t.Error("unexpected match")
+	}
+}
+
+func TestTriageConditionalRequest(t *testing.T) {
+	const lastModified = "Mon, 04 Aug 2025 00:00:00 GMT"
+	var ifModifiedSince []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/notifications" {
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+		ims := r.Header.Get("If-Modified-Since")
+		ifModifiedSince = append(ifModifiedSince, ims)
+		w.Header().Set("X-Poll-Interval", "60")
+		if ims == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", lastModified)
+		_, _ = w.Write([]byte("[]"))
+	}))
+	defer ts.Close()
+	c := newTestClient(t, &profile.Profile{}, ts, io.Discard)
+
+	for range 2 {
+		if err := c.Triage(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(ifModifiedSince) != 2 || ifModifiedSince[0] != "" || ifModifiedSince[1] != lastModified {
+		t.Errorf("got If-Modified-Since %q", ifModifiedSince)
+	}
+	if got := c.PollInterval(); got != time.Minute {
+		t.Errorf("got poll interval %s, want %s", got, time.Minute)
+	}
+}
+
+func TestNotificationsQuery(t *testing.T) {
+	now := time.Date(2025, 8, 4, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		cfg  *profile.Profile
+		want string
+	}{
+		{"default", &profile.Profile{}, "per_page=100"},
+		{"all and participating", &profile.Profile{All: true, Participating: true}, "all=true&participating=true&per_page=100"},
+		{"since and before", &profile.Profile{Since: "7d", Before: "2025-08-01T00:00:00Z"}, "before=2025-08-01T00%3A00%3A00Z&per_page=100&since=2025-07-28T12%3A00%3A00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{config: tt.cfg}
+			q, err := c.notificationsQuery(now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := q.Encode(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSourcesAndScope(t *testing.T) {
+	notification := func(owner, repo string) *github.Notification {
+		return &github.Notification{Repository: &github.Repository{Name: github.Ptr(repo), Owner: &github.User{Login: github.Ptr(owner)}}}
+	}
+	tests := []struct {
+		name        string
+		cfg         *profile.Profile
+		wantSources []string
+		wantInScope []bool // k1LoW/gh-triage, k1LoW/tbls, octocat/hello
+	}{
+		{"no scope", &profile.Profile{}, []string{"notifications"}, []bool{true, true, true}},
+		{"repositories", &profile.Profile{Repositories: []string{"k1LoW/gh-triage", "octocat/Hello"}}, []string{"repos/k1LoW/gh-triage/notifications", "repos/octocat/Hello/notifications"}, []bool{true, false, true}},
+		{"owners", &profile.Profile{Owners: []string{"k1low"}}, []string{"notifications"}, []bool{true, true, false}},
+		{"repositories and owners", &profile.Profile{Repositories: []string{"octocat/hello"}, Owners: []string{"k1LoW"}}, []string{"notifications"}, []bool{true, true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{config: tt.cfg}
+			if got := c.sources(); !slices.Equal(got, tt.wantSources) {
+				t.Errorf("got sources %v, want %v", got, tt.wantSources)
+			}
+			for i, n := range []*github.Notification{notification("k1LoW", "gh-triage"), notification("k1LoW", "tbls"), notification("octocat", "hello")} {
+				if got := c.inScope(n); got != tt.wantInScope[i] {
+					t.Errorf("inScope(%s/%s) = %v, want %v", n.GetRepository().GetOwner().GetLogin(), n.GetRepository().GetName(), got, tt.wantInScope[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTriageContinuesOnItemError(t *testing.T) {
+	notifications := `[
+  {"id": "1", "unread": true, "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "Broken", "url": "https://api.github.com/repos/owner/repo/issues/1", "type": "Issue"}},
+  {"id": "2", "unread": true, "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "Fine", "url": "https://api.github.com/repos/owner/repo/issues/2", "type": "Issue"}}
+]`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/notifications":
+			if r.URL.Query().Get("page") == "1" {
+				_, _ = io.WriteString(w, notifications)
+				return
+			}
+			_, _ = io.WriteString(w, "[]")
+		case "/rate_limit":
+			_, _ = io.WriteString(w, `{"resources":{"core":{"limit":5000,"remaining":5000}}}`)
+		case "/user":
+			_, _ = io.WriteString(w, `{"login":"me"}`)
+		case "/user/teams", "/user/orgs":
+			_, _ = io.WriteString(w, "[]")
+		case "/graphql":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/repos/owner/repo/issues/1":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/repos/owner/repo/issues/2":
+			_, _ = io.WriteString(w, `{"number":2,"state":"open","html_url":"https://github.com/owner/repo/issues/2"}`)
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	cfg := &profile.Profile{Rules: []profile.Rule{{Max: 10, Conditions: []string{"*"}, Actions: []profile.RuleAction{{Type: profile.ActionList}}}}}
+
+	t.Run("continue", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		c := newTestClient(t, cfg, ts, out)
+		err := c.Triage(context.Background())
+		var triageErr *TriageError
+		if !errors.As(err, &triageErr) {
+			t.Fatalf("Expected TriageError, got %v", err)
+		}
+		if triageErr.Total != 2 || len(triageErr.Errors) != 1 || triageErr.Errors[0].ThreadID != "1" {
+			t.Errorf("got %+v", triageErr)
+		}
+		if got := triageErr.Counts(); got["HTTP 502"] != 1 {
+			t.Errorf("got counts %v", got)
+		}
+		if !strings.Contains(out.String(), "Fine") {
+			t.Errorf("Expected the other notification to be listed, got %q", out.String())
+		}
+	})
+
+	t.Run("fail fast", func(t *testing.T) {
+		c := newTestClient(t, cfg, ts, io.Discard, WithFailFast(true))
+		err := c.Triage(context.Background())
+		var triageErr *TriageError
+		if err == nil || errors.As(err, &triageErr) {
+			t.Errorf("Expected the error of the notification, got %v", err)
+		}
+	})
+}
+
+func TestTriageLegacyListAfterOtherActions(t *testing.T) {
+	notifications := `[
+  {"id": "1", "unread": true, "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "Closed", "url": "https://api.github.com/repos/owner/repo/issues/1", "type": "Issue"}},
+  {"id": "2", "unread": true, "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "Open", "url": "https://api.github.com/repos/owner/repo/issues/2", "type": "Issue"}}
+]`
+	var done []string
+	ts := newFakeGitHub(t, notifications, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/owner/repo/issues/1":
+			_, _ = io.WriteString(w, `{"number":1,"state":"closed","closed_at":"2025-01-01T00:00:00Z"}`)
+		case r.URL.Path == "/repos/owner/repo/issues/2":
+			_, _ = io.WriteString(w, `{"number":2,"state":"open"}`)
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/notifications/threads/"):
+			done = append(done, path.Base(r.URL.Path))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer ts.Close()
+	cfg := &profile.Profile{
+		Done:        profile.Action{Max: 10, Conditions: []string{"closed"}},
+		List:        profile.Action{Max: 10, Conditions: []string{"unread"}, Template: "{{ .title }}"},
+		Concurrency: 1,
+	}
+	out := new(bytes.Buffer)
+	c := newTestClient(t, cfg, ts, out)
+	if err := c.Triage(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(done, []string{"1"}) {
+		t.Errorf("got done %v", done)
+	}
+	// The notification marked as done is no longer unread when the list rule is evaluated.
+	if got := out.String(); got != "Open\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestTriageUnchangedNotifications(t *testing.T) {
+	notifications := `[
+  {"id": "1", "unread": true, "updated_at": "2025-01-01T00:00:00Z", "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "A", "url": "https://api.github.com/repos/owner/repo/issues/1", "type": "Issue"}},
+  {"id": "2", "unread": true, "updated_at": "2025-01-01T00:00:00Z", "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "B", "url": "https://api.github.com/repos/owner/repo/issues/2", "type": "Issue"}}
+]`
+	tests := []struct {
+		name string
+		rule profile.Rule
+		want string
+	}{
+		{
+			"unchanged",
+			profile.Rule{Max: 10, Conditions: []string{"open"}},
+			"A\nB\n",
+		},
+		{
+			"capped by max",
+			profile.Rule{Name: "capped", Max: 1, Conditions: []string{"open"}, Actions: []profile.RuleAction{{Type: profile.ActionList, Once: true}}},
+			"A\nB\n",
+		},
+		{
+			"time-based condition",
+			profile.Rule{Max: 10, Conditions: []string{"open && older_than(updated_at, '1h')"}},
+			"A\nB\nA\nB\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newFakeGitHub(t, notifications, func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprintf(w, `{"number":%s,"state":"open"}`, path.Base(r.URL.Path))
+			})
+			defer ts.Close()
+			st, err := state.Open(filepath.Join(t.TempDir(), "default.state.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tt.rule.Actions) == 0 {
+				tt.rule.Actions = []profile.RuleAction{{Type: profile.ActionList}}
+			}
+			tt.rule.Actions[0].Template = "{{ .title }}"
+			cfg := &profile.Profile{Rules: []profile.Rule{tt.rule}, Concurrency: 1}
+			out := new(bytes.Buffer)
+			c := newTestClient(t, cfg, ts, out, WithStore(st))
+			// Two ticks of --watch, the second of which gets 304 Not Modified for the notifications.
+			for range 2 {
+				if err := c.Triage(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTriageOnce(t *testing.T) {
+	notifications := `[
+  {"id": "1", "unread": true, "updated_at": "2025-01-01T00:00:00Z", "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "A", "url": "https://api.github.com/repos/owner/repo/issues/1", "type": "Issue"}}
+]`
+	tests := []struct {
+		name      string
+		once      bool
+		want      string
+		wantState bool
+	}{
+		{"once", true, "a A\nb A\n", true},
+		{"always", false, "a A\nb A\na A\nb A\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newFakeGitHub(t, notifications, func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, `{"number":1,"state":"open"}`)
+			})
+			defer ts.Close()
+			p := filepath.Join(t.TempDir(), "default.state.json")
+			// Two actions of the same type in a rule are recorded separately.
+			cfg := &profile.Profile{Rules: []profile.Rule{{Name: "review", Max: 10, Conditions: []string{"open"}, Actions: []profile.RuleAction{
+				{Type: profile.ActionList, Template: "a {{ .title }}", Once: tt.once},
+				{Type: profile.ActionList, Template: "b {{ .title }}", Once: tt.once},
+			}}}}
+			out := new(bytes.Buffer)
+			// Separate runs sharing the state file.
+			for range 2 {
+				st, err := state.Open(p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				c := newTestClient(t, cfg, ts, out, WithStore(st))
+				if err := c.Triage(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			// The state file is written only for actions with once.
+			if _, err := os.Stat(p); (err == nil) != tt.wantState {
+				t.Errorf("got state file error %v, want state file %v", err, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestTriageDryRun(t *testing.T) {
+	notifications := `[
+  {"id": "1", "unread": true, "updated_at": "2025-01-01T00:00:00Z", "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "Fix bug", "url": "https://api.github.com/repos/owner/repo/pulls/1", "type": "PullRequest"}},
+  {"id": "2", "unread": true, "updated_at": "2025-01-01T00:00:00Z", "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "Q&A", "url": "https://api.github.com/repos/owner/repo/discussions/2", "type": "Discussion"}}
+]`
+	fake := fakeGitHubHandler(notifications, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/pulls/1":
+			_, _ = io.WriteString(w, `{"number":1,"state":"open","labels":[{"name":"triage"}]}`)
+		case "/repos/owner/repo/pulls/1/reviews":
+			_, _ = io.WriteString(w, "[]")
+		default:
+			_, _ = io.WriteString(w, "{}")
+		}
+	})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// GraphQL queries are POST too, so mutations are told by the query.
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if (r.Method != http.MethodGet && r.URL.Path != "/graphql") || strings.Contains(string(b), "mutation") {
+			t.Errorf("unexpected mutation in dry-run: %s %s %s", r.Method, r.URL.Path, b)
+		}
+		if r.URL.Path == "/graphql" && strings.Contains(string(b), "discussion(number: $number)") {
+			_, _ = io.WriteString(w, `{"data":{"repository":{"discussion":{"title":"Q&A","url":"https://github.com/owner/repo/discussions/2","number":2,"labels":{"nodes":[{"name":"triage"}]},"author":{"login":"alice"}}}}}`)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(b))
+		fake(w, r)
+	}))
+	defer ts.Close()
+	dir := t.TempDir()
+	st, err := state.Open(filepath.Join(dir, "default.state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+	n := &recordNotifier{}
+	cfg := &profile.Profile{
+		Rules: []profile.Rule{{Name: "everything", Max: 10, Conditions: []string{"*"}, Actions: []profile.RuleAction{
+			{Type: profile.ActionLabel, Labels: []string{"bug"}, Once: true},
+			{Type: profile.ActionUnlabel, Labels: []string{"triage"}},
+			{Type: profile.ActionComment, Body: "Thanks!"},
+			{Type: profile.ActionAssign},
+			{Type: profile.ActionRequestReview, Reviewers: []string{"alice"}},
+			{Type: profile.ActionExec, Command: "touch " + out},
+			{Type: profile.ActionWebhook, URL: ts.URL + "/hooks"},
+			{Type: profile.ActionNotify},
+			{Type: profile.ActionRead},
+			{Type: profile.ActionUnsubscribe},
+			{Type: profile.ActionDone},
+		}}},
+		Concurrency: 1,
+	}
+	buf := new(bytes.Buffer)
+	c := newTestClient(t, cfg, ts, buf, WithDryRun(true), WithStore(st), WithNotifier(n))
+	if err := c.Triage(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Error("Expected the command not to run in dry-run")
+	}
+	if len(n.titles) != 0 {
+		t.Errorf("Expected nothing to be announced in dry-run, got %v", n.titles)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "default.state.json")); err == nil {
+		t.Error("Expected the state not to be saved in dry-run")
+	}
+	for _, a := range []string{profile.ActionLabel, profile.ActionRequestReview, profile.ActionWebhook, profile.ActionDone} {
+		if !strings.Contains(buf.String(), a) {
+			t.Errorf("Expected %s to be reported, got %s", a, buf.String())
+		}
+	}
+	for _, a := range []string{profile.ActionLabel, profile.ActionUnlabel, profile.ActionComment, profile.ActionDone} {
+		if want := fmt.Sprintf("[dry-run] %-14s owner/repo #2\n", a); !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %s on the discussion to be reported, got %s", a, buf.String())
+		}
+	}
+}
+
+func TestTriageDryRunFormat(t *testing.T) {
+	notifications := `[
+  {"id": "1", "unread": true, "updated_at": "2025-01-01T00:00:00Z", "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "Fix bug", "url": "https://api.github.com/repos/owner/repo/issues/1", "type": "Issue"}}
+]`
+	ts := newFakeGitHub(t, notifications, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"number":1,"state":"open"}`)
+	})
+	defer ts.Close()
+	cfg := &profile.Profile{
+		Rules: []profile.Rule{{Max: 10, Conditions: []string{"*"}, Actions: []profile.RuleAction{
+			{Type: profile.ActionList},
+			{Type: profile.ActionDone},
+		}}},
+	}
+	out := new(bytes.Buffer)
+	errOut := new(bytes.Buffer)
+	c := newTestClient(t, cfg, ts, out, WithDryRun(true), WithFormat(FormatJSON))
+	c.errW = errOut
+	if err := c.Triage(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("Expected the output to be JSON: %v\n%s", err, out.String())
+	}
+	if len(got) != 1 || got[0]["title"] != "Fix bug" {
+		t.Errorf("got %v", got)
+	}
+	if !strings.Contains(errOut.String(), profile.ActionDone) {
+		t.Errorf("Expected done to be reported to stderr, got %q", errOut.String())
+	}
+}
+
+func TestFields(t *testing.T) {
+	updatedAt := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	lastReadAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		notification string
+		want         map[string]any
+	}{
+		{
+			"notification",
+			`{"id": "42", "reason": "review_requested", "unread": true, "updated_at": "2025-01-02T00:00:00Z", "last_read_at": "2025-01-01T00:00:00Z", "repository": {"name": "repo", "owner": {"login": "owner"}}, "subject": {"title": "Fix bug", "url": "https://api.github.com/repos/owner/repo/issues/1", "type": "Issue"}}`,
+			map[string]any{
+				"thread_id":    "42",
+				"reason":       "review_requested",
+				"subject_type": "Issue",
+				"updated_at":   updatedAt,
+				"last_read_at": lastReadAt,
+				"title":        "Fix bug",
+				"owner":        "owner",
+				"repo":         "repo",
+				"me":           "me",
+				"unread":       true,
+				"is_issue":     true,
+				"number":       1,
+			},
+		},
+		{
+			"read",
+			`{"id": "46", "reason": "subscribed", "unread": false, "updated_at": "2025-01-02T00:00:00Z", "last_read_at": "2025-01-02T00:00:00Z", "repository": {"name": "repo", "owner": {"login": "owner"}}, "subject": {"title": "Fix bug", "url": "https://api.github.com/repos/owner/repo/issues/1", "type": "Issue"}}`,
+			map[string]any{
+				"thread_id":    "46",
+				"unread":       false,
+				"last_read_at": updatedAt,
+			},
+		},
+		{
+			"never read",
+			`{"id": "43", "unread": true, "reason": "mention", "updated_at": "2025-01-02T00:00:00Z", "repository": {"name": "repo", "owner": {"login": "owner"}}, "subject": {"title": "Fix bug", "url": "https://api.github.com/repos/owner/repo/issues/1", "type": "Issue"}}`,
+			map[string]any{
+				"thread_id":    "43",
+				"reason":       "mention",
+				"last_read_at": time.Time{},
+			},
+		},
+		{
+			"release",
+			`{"id": "44", "unread": true, "reason": "subscribed", "updated_at": "2025-01-02T00:00:00Z", "repository": {"name": "repo", "owner": {"login": "owner"}}, "subject": {"title": "v1.0.0-rc.1", "url": "https://api.github.com/repos/owner/repo/releases/100", "type": "Release"}}`,
+			map[string]any{
+				"subject_type":     "Release",
+				"is_release":       true,
+				"release_id":       100,
+				"number":           -1,
+				"tag_name":         "v1.0.0-rc.1",
+				"target_commitish": "main",
+				"prerelease":       true,
+				"draft":            false,
+				"author":           "alice",
+				"html_url":         "https://github.com/owner/repo/releases/tag/v1.0.0-rc.1",
+				"created_at":       lastReadAt,
+				"published_at":     updatedAt,
+			},
+		},
+		{
+			"workflow run",
+			`{"id": "47", "unread": true, "reason": "ci_activity", "updated_at": "2025-01-02T00:00:00Z", "repository": {"name": "repo", "html_url": "https://github.com/owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "CI workflow run failed for main branch", "type": "WorkflowRun"}}`,
+			map[string]any{
+				"subject_type":    "WorkflowRun",
+				"is_workflow_run": true,
+				"is_check_suite":  false,
+				"workflow":        "CI",
+				"conclusion":      "failed",
+				"branch":          "main",
+				"failed":          true,
+				"html_url":        "https://github.com/owner/repo/actions",
+			},
+		},
+		{
+			"deleted release",
+			`{"id": "45", "unread": true, "repository": {"name": "repo", "owner": {"login": "owner"}}, "subject": {"title": "v0.1.0", "url": "https://api.github.com/repos/owner/repo/releases/404", "type": "Release"}}`,
+			nil,
+		},
+	}
+	ts := newFakeGitHub(t, "[]", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/issues/1":
+			_, _ = io.WriteString(w, `{"number":1,"state":"open"}`)
+		case "/repos/owner/repo/releases/100":
+			_, _ = io.WriteString(w, `{"id":100,"tag_name":"v1.0.0-rc.1","target_commitish":"main","prerelease":true,"draft":false,"author":{"login":"alice"},"html_url":"https://github.com/owner/repo/releases/tag/v1.0.0-rc.1","created_at":"2025-01-01T00:00:00Z","published_at":"2025-01-02T00:00:00Z"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"message":"Not Found"}`)
+		}
+	})
+	defer ts.Close()
+	c := newTestClient(t, &profile.Profile{}, ts, io.Discard)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &github.Notification{}
+			if err := json.Unmarshal([]byte(tt.notification), n); err != nil {
+				t.Fatal(err)
+			}
+			m, err := c.fields(context.Background(), n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if m != nil {
+					t.Errorf("Expected the notification to be skipped, got %v", m)
+				}
+				return
+			}
+			for k, want := range tt.want {
+				if got := m[k]; !reflect.DeepEqual(got, want) {
+					t.Errorf("got %s %v, want %v", k, got, want)
+				}
+			}
+		})
+	}
+}
+
+// newFakeGitHub returns a server that responds to the notifications and the authenticated user,
+// and passes the other requests to the handler. GraphQL requests fail so that subjects are fetched with the REST API.
+// The notifications never change, so conditional requests for them get 304 Not Modified.
+func newFakeGitHub(t *testing.T, notifications string, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(fakeGitHubHandler(notifications, handler))
+}
+
+// fakeGitHubHandler returns the handler of newFakeGitHub.
+func fakeGitHubHandler(notifications string, handler http.HandlerFunc) http.HandlerFunc {
+	const lastModified = "Mon, 04 Aug 2025 00:00:00 GMT"
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/notifications":
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Last-Modified", lastModified)
+			if r.URL.Query().Get("page") == "1" {
+				_, _ = io.WriteString(w, notifications)
+				return
+			}
+			_, _ = io.WriteString(w, "[]")
+		case r.URL.Path == "/rate_limit":
+			_, _ = io.WriteString(w, `{"resources":{"core":{"limit":5000,"remaining":5000}}}`)
+		case r.URL.Path == "/user":
+			_, _ = io.WriteString(w, `{"login":"me"}`)
+		case r.URL.Path == "/user/teams", r.URL.Path == "/user/orgs":
+			_, _ = io.WriteString(w, "[]")
+		case r.URL.Path == "/graphql":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			handler(w, r)
+		}
+	}
+}
+
+func newTestClient(t *testing.T, cfg *profile.Profile, ts *httptest.Server, w io.Writer, opts ...Option) *Client {
+	t.Helper()
+	client := github.NewClient(ts.Client())
+	u, err := url.Parse(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.BaseURL = u
+	c, err := newClient(cfg, client, w, false, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.v4Client = githubv4.NewEnterpriseClient(ts.URL+"/graphql", ts.Client())
+	return c
+}
@@ -0,0 +1,196 @@
+package gh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/k1LoW/gh-triage/cond"
+	"github.com/k1LoW/gh-triage/history"
+	"github.com/k1LoW/gh-triage/profile"
+	"github.com/samber/lo"
+)
+
+// mutations are the action types recorded in the history.
+var mutations = []string{
+	profile.ActionDone, profile.ActionUnsubscribe, profile.ActionRead,
+	profile.ActionLabel, profile.ActionUnlabel, profile.ActionComment, profile.ActionAssign, profile.ActionRequestReview,
+}
+
+// Undo reverts the mutations of the run recorded in the history, in reverse order.
+// Mutations that can not be reverted through the API, such as done and read, are listed with their URLs to re-surface them by hand.
+// They are recorded as undone too, so that the run is not picked as the last run again.
+func (c *Client) Undo(ctx context.Context, runID string) error {
+	if c.history == nil {
+		return errors.New("history is not available")
+	}
+	entries, err := c.history.Entries()
+	if err != nil {
+		return err
+	}
+	if runID == "" {
+		runID = history.LastRun(entries)
+		if runID == "" {
+			return errors.New("no run to undo")
+		}
+	}
+	run := history.Run(entries, runID)
+	if len(run) == 0 {
+		return fmt.Errorf("no mutations in run %s", runID)
+	}
+	undoRunID := history.NewRunID()
+	for _, e := range slices.Backward(run) {
+		reverted, err := c.revert(ctx, e)
+		if err != nil {
+			return err
+		}
+		mark := undoC.Sprint("[undo]")
+		if !reverted {
+			mark = dryRunC.Sprint("[manual]")
+		}
+		if c.dryRun {
+			mark = dryRunC.Sprint("[dry-run]")
+		}
+		if _, err := fmt.Fprintf(c.w, "%s %-14s %s\n  %s ( %s )\n", mark, e.Action, numberC.Sprint(entryRef(e)), titleC.Sprint(e.Title), e.HTMLURL); err != nil {
+			return err
+		}
+		if c.dryRun {
+			continue
+		}
+		e.RunID = undoRunID
+		e.Time = time.Now()
+		e.Undo = runID
+		e.Manual = !reverted
+		if err := c.history.Append(e); err != nil {
+			return fmt.Errorf("failed to write history: %w", err)
+		}
+	}
+	return nil
+}
+
+// historyEntry returns the entry of the mutation that the action is about to perform, or nil if the action is not a mutation.
+// Labels, assignees and reviewers are limited to ones actually changed by the action, so that undo does not revert more than that.
+func (c *Client) historyEntry(r profile.Rule, a profile.RuleAction, m map[string]any) *history.Entry {
+	if c.history == nil || c.dryRun || !slices.Contains(mutations, a.Type) {
+		return nil
+	}
+	if a.Type != profile.ActionDone && a.Type != profile.ActionUnsubscribe && a.Type != profile.ActionRead && !editable(a.Type, m) {
+		return nil
+	}
+	condition, _ := cond.Matched(r.Conditions, m)
+	e := &history.Entry{
+		RunID:     c.runID,
+		Time:      time.Now(),
+		Rule:      r.DisplayName(),
+		Condition: condition,
+		Action:    a.Type,
+	}
+	e.ThreadID, _ = m["thread_id"].(string)
+	e.Owner, _ = m["owner"].(string)
+	e.Repo, _ = m["repo"].(string)
+	e.SubjectType, _ = m["subject_type"].(string)
+	e.Title, _ = m["title"].(string)
+	e.HTMLURL, _ = m["html_url"].(string)
+	if number, ok := m["number"].(int); ok && number > 0 {
+		e.Number = number
+	}
+	labels, _ := m["labels"].([]string)
+	switch a.Type {
+	case profile.ActionLabel:
+		e.Labels = lo.Without(a.Labels, labels...)
+	case profile.ActionUnlabel:
+		e.Labels = lo.Intersect(a.Labels, labels)
+	case profile.ActionAssign:
+		assignees := a.Assignees
+		if len(assignees) == 0 {
+			me, _ := m["me"].(string)
+			assignees = []string{me}
+		}
+		current, _ := m["assignees"].([]string)
+		e.Assignees = lo.Without(assignees, current...)
+	case profile.ActionRequestReview:
+		reviewers, _ := m["reviewers"].([]string)
+		e.Reviewers = lo.Without(a.Reviewers, reviewers...)
+		slugs, _ := m["review_team_slugs"].([]string)
+		e.TeamReviewers = lo.Filter(a.TeamReviewers, func(t string, _ int) bool {
+			return !slices.Contains(slugs, e.Owner+"/"+t)
+		})
+	}
+	return e
+}
+
+// revert reverts the mutation of the entry, and reports whether it was reverted.
+func (c *Client) revert(ctx context.Context, e history.Entry) (bool, error) {
+	switch e.Action {
+	case profile.ActionUnsubscribe:
+		if c.dryRun {
+			return true, nil
+		}
+		if _, _, err := c.client.Activity.SetThreadSubscription(ctx, e.ThreadID, &github.Subscription{Ignored: github.Ptr(false)}); err != nil {
+			return false, fmt.Errorf("failed to resubscribe to %s: %w", entryRef(e), err)
+		}
+		return true, nil
+	case profile.ActionLabel, profile.ActionUnlabel:
+		if len(e.Labels) == 0 || c.dryRun {
+			return true, nil
+		}
+		add := e.Action == profile.ActionUnlabel
+		if e.SubjectType == "Discussion" {
+			if err := c.labelDiscussion(ctx, e.Owner, e.Repo, e.Number, e.Labels, add); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+		if add {
+			if _, _, err := c.client.Issues.AddLabelsToIssue(ctx, e.Owner, e.Repo, e.Number, e.Labels); err != nil {
+				return false, fmt.Errorf("failed to add labels to %s: %w", entryRef(e), err)
+			}
+			return true, nil
+		}
+		for _, l := range e.Labels {
+			if _, err := c.client.Issues.RemoveLabelForIssue(ctx, e.Owner, e.Repo, e.Number, l); err != nil {
+				var errResp *github.ErrorResponse
+				if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound {
+					continue
+				}
+				return false, fmt.Errorf("failed to remove label from %s: %w", entryRef(e), err)
+			}
+		}
+		return true, nil
+	case profile.ActionAssign:
+		if len(e.Assignees) == 0 || c.dryRun {
+			return true, nil
+		}
+		if _, _, err := c.client.Issues.RemoveAssignees(ctx, e.Owner, e.Repo, e.Number, e.Assignees); err != nil {
+			return false, fmt.Errorf("failed to remove assignees from %s: %w", entryRef(e), err)
+		}
+		return true, nil
+	case profile.ActionRequestReview:
+		if (len(e.Reviewers) == 0 && len(e.TeamReviewers) == 0) || c.dryRun {
+			return true, nil
+		}
+		if _, err := c.client.PullRequests.RemoveReviewers(ctx, e.Owner, e.Repo, e.Number, github.ReviewersRequest{
+			Reviewers:     e.Reviewers,
+			TeamReviewers: e.TeamReviewers,
+		}); err != nil {
+			return false, fmt.Errorf("failed to remove reviewers from %s: %w", entryRef(e), err)
+		}
+		return true, nil
+	default:
+		// Notifications can not be marked as unread or not done, and comments are not deleted.
+		return false, nil
+	}
+}
+
+// entryRef returns the reference of the subject of the entry such as owner/repo #number.
+func entryRef(e history.Entry) string {
+	r := e.Owner + "/" + e.Repo
+	if e.Number > 0 {
+		r += fmt.Sprintf(" #%d", e.Number)
+	}
+	return r
+}
@@ -0,0 +1,175 @@
+package gh
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/k1LoW/gh-triage/history"
+	"github.com/k1LoW/gh-triage/profile"
+)
+
+func TestHistoryEntry(t *testing.T) {
+	c := &Client{
+		history: history.New(filepath.Join(t.TempDir(), "default.history.jsonl")),
+		runID:   "run",
+	}
+	r := profile.Rule{Name: "triage", Conditions: []string{"is_release", "is_pull_request && open"}}
+	m := map[string]any{
+		"thread_id":         "1",
+		"owner":             "k1LoW",
+		"repo":              "gh-triage",
+		"number":            1,
+		"me":                "alice",
+		"subject_type":      "PullRequest",
+		"is_issue":          false,
+		"is_pull_request":   true,
+		"is_discussion":     false,
+		"is_release":        false,
+		"open":              true,
+		"labels":            []string{"bug"},
+		"assignees":         []string{},
+		"reviewers":         []string{"bob"},
+		"review_team_slugs": []string{"k1LoW/core"},
+	}
+	tests := []struct {
+		name   string
+		action profile.RuleAction
+		want   *history.Entry
+	}{
+		{"not a mutation", profile.RuleAction{Type: profile.ActionList}, nil},
+		{"done", profile.RuleAction{Type: profile.ActionDone}, &history.Entry{}},
+		{"label only new labels", profile.RuleAction{Type: profile.ActionLabel, Labels: []string{"bug", "triaged"}}, &history.Entry{Labels: []string{"triaged"}}},
+		{"unlabel only existing labels", profile.RuleAction{Type: profile.ActionUnlabel, Labels: []string{"bug", "triaged"}}, &history.Entry{Labels: []string{"bug"}}},
+		{"assign me", profile.RuleAction{Type: profile.ActionAssign}, &history.Entry{Assignees: []string{"alice"}}},
+		{"request review only new reviewers", profile.RuleAction{Type: profile.ActionRequestReview, Reviewers: []string{"bob", "carol"}, TeamReviewers: []string{"core", "docs"}}, &history.Entry{Reviewers: []string{"carol"}, TeamReviewers: []string{"docs"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.historyEntry(r, tt.action, m)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("got %v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("got nil")
+			}
+			if got.RunID != "run" || got.ThreadID != "1" || got.Rule != "triage" || got.Condition != "is_pull_request && open" || got.Action != tt.action.Type {
+				t.Errorf("got %+v", got)
+			}
+			if !slices.Equal(got.Labels, tt.want.Labels) || !slices.Equal(got.Assignees, tt.want.Assignees) || !slices.Equal(got.Reviewers, tt.want.Reviewers) || !slices.Equal(got.TeamReviewers, tt.want.TeamReviewers) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	c.dryRun = true
+	if got := c.historyEntry(r, profile.RuleAction{Type: profile.ActionDone}, m); got != nil {
+		t.Errorf("Expected no entry in dry-run, got %v", got)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	h := history.New(filepath.Join(t.TempDir(), "default.history.jsonl"))
+	entry := func(runID, action string, e history.Entry) history.Entry {
+		e.RunID = runID
+		e.ThreadID = "1"
+		e.Owner = "owner"
+		e.Repo = "repo"
+		e.Number = 1
+		e.SubjectType = "PullRequest"
+		e.Action = action
+		return e
+	}
+	for _, e := range []history.Entry{
+		entry("run1", profile.ActionRead, history.Entry{}),
+		entry("run2", profile.ActionUnsubscribe, history.Entry{}),
+		entry("run2", profile.ActionLabel, history.Entry{Labels: []string{"triaged"}}),
+		entry("run2", profile.ActionUnlabel, history.Entry{Labels: []string{"bug"}}),
+		entry("run2", profile.ActionAssign, history.Entry{Assignees: []string{"alice"}}),
+		entry("run2", profile.ActionRequestReview, history.Entry{Reviewers: []string{"bob"}, TeamReviewers: []string{"core"}}),
+		entry("run2", profile.ActionComment, history.Entry{}),
+		entry("run2", profile.ActionDone, history.Entry{}),
+	} {
+		if err := h.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var reqs, bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		reqs = append(reqs, r.Method+" "+r.URL.Path)
+		bodies = append(bodies, string(b))
+		if strings.HasSuffix(r.URL.Path, "/labels") {
+			_, _ = io.WriteString(w, "[]")
+			return
+		}
+		_, _ = io.WriteString(w, "{}")
+	}))
+	defer ts.Close()
+	out := new(bytes.Buffer)
+	c := newTestClient(t, &profile.Profile{}, ts, out, WithHistory(h))
+	if err := c.Undo(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+	wantReqs := []string{
+		"DELETE /repos/owner/repo/pulls/1/requested_reviewers",
+		"DELETE /repos/owner/repo/issues/1/assignees",
+		"POST /repos/owner/repo/issues/1/labels",
+		"DELETE /repos/owner/repo/issues/1/labels/triaged",
+		"PUT /notifications/threads/1/subscription",
+	}
+	if !reflect.DeepEqual(reqs, wantReqs) {
+		t.Fatalf("got requests %v, want %v", reqs, wantReqs)
+	}
+	for i, want := range []string{`"reviewers":["bob"],"team_reviewers":["core"]`, `"assignees":["alice"]`, `["bug"]`, "", `"ignored":false`} {
+		if !strings.Contains(bodies[i], want) {
+			t.Errorf("got body %s, want containing %s", bodies[i], want)
+		}
+	}
+	if got := strings.Count(out.String(), "[manual]"); got != 2 {
+		t.Errorf("Expected done and comment to be reverted by hand, got %s", out.String())
+	}
+
+	entries, err := h.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	undone := entries[8:]
+	if len(undone) != 7 {
+		t.Fatalf("got %d undo entries, want 7", len(undone))
+	}
+	for _, e := range undone {
+		manual := e.Action == profile.ActionDone || e.Action == profile.ActionComment
+		if e.Undo != "run2" || e.Manual != manual {
+			t.Errorf("got %+v", e)
+		}
+	}
+	if got := history.LastRun(entries); got != "run1" {
+		t.Errorf("got last run %s, want run1", got)
+	}
+
+	// A run with only mutations to be reverted by hand is not picked again.
+	reqs = nil
+	if err := c.Undo(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 0 {
+		t.Errorf("got requests %v, want none", reqs)
+	}
+	if err := c.Undo(context.Background(), ""); err == nil || err.Error() != "no run to undo" {
+		t.Errorf("got error %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/samber/lo"
+)
+
+// identity is the authenticated user and the teams/organizations the user belongs to.
+type identity struct {
+	login string
+	teams []string // Team slugs in the form of org/slug
+	orgs  []string // Organization logins
+}
+
+// identity returns the authenticated user, resolving it only once per Client.
+func (c *Client) identity(ctx context.Context) (*identity, error) {
+	c.meMu.Lock()
+	defer c.meMu.Unlock()
+	if c.me != nil {
+		return c.me, nil
+	}
+	u, _, err := c.client.Users.Get(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authenticated user: %w", err)
+	}
+	id := &identity{
+		login: u.GetLogin(),
+		teams: []string{},
+		orgs:  []string{},
+	}
+
+	// Teams and organizations require the read:org scope, so missing them is not fatal.
+	page := 1
+	for {
+		teams, res, err := c.client.Teams.ListUserTeams(ctx, &github.ListOptions{Page: page, PerPage: 100})
+		if err != nil {
+			slog.Warn("Failed to list teams of authenticated user", "error", err)
+			break
+		}
+		id.teams = append(id.teams, lo.Map(teams, func(t *github.Team, _ int) string {
+			return t.GetOrganization().GetLogin() + "/" + t.GetSlug()
+		})...)
+		if res.NextPage == 0 {
+			break
+		}
+		page = res.NextPage
+	}
+	page = 1
+	for {
+		orgs, res, err := c.client.Organizations.List(ctx, "", &github.ListOptions{Page: page, PerPage: 100})
+		if err != nil {
+			slog.Warn("Failed to list organizations of authenticated user", "error", err)
+			break
+		}
+		id.orgs = append(id.orgs, lo.Map(orgs, func(o *github.Organization, _ int) string {
+			return o.GetLogin()
+		})...)
+		if res.NextPage == 0 {
+			break
+		}
+		page = res.NextPage
+	}
+
+	c.me = id
+	return id, nil
+}
@@ -0,0 +1,69 @@
+package gh
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/k1LoW/gh-triage/profile"
+)
+
+func TestIdentity(t *testing.T) {
+	notifications := `[
+  {"id": "1", "unread": true, "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "A", "url": "https://api.github.com/repos/owner/repo/issues/1", "type": "Issue"}},
+  {"id": "2", "unread": true, "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "B", "url": "https://api.github.com/repos/owner/repo/issues/2", "type": "Issue"}},
+  {"id": "3", "unread": true, "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "C", "url": "https://api.github.com/repos/owner/repo/issues/3", "type": "Issue"}}
+]`
+	var userReqs, teamsReqs atomic.Int32
+	fake := fakeGitHubHandler(notifications, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"number":1,"state":"open"}`)
+	})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user":
+			userReqs.Add(1)
+			_, _ = io.WriteString(w, `{"login":"alice"}`)
+		case "/user/teams":
+			// Without the read:org scope.
+			teamsReqs.Add(1)
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `{"message":"Resource not accessible by integration"}`)
+		case "/user/orgs":
+			_, _ = io.WriteString(w, `[{"login":"k1LoW"}]`)
+		default:
+			fake(w, r)
+		}
+	}))
+	defer ts.Close()
+	cfg := &profile.Profile{
+		Rules: []profile.Rule{{Max: 10, Conditions: []string{"me == 'alice' && 'k1LoW' in my_orgs && len(my_teams) == 0"}, Actions: []profile.RuleAction{
+			{Type: profile.ActionList, Template: "{{ .title }}"},
+		}}},
+		Concurrency: 3,
+	}
+	out := new(bytes.Buffer)
+	c := newTestClient(t, cfg, ts, out)
+	if err := c.Triage(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.identity(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := userReqs.Load(); got != 1 {
+		t.Errorf("got %d requests for the authenticated user, want 1", got)
+	}
+	if got := teamsReqs.Load(); got != 1 {
+		t.Errorf("got %d requests for the teams, want 1", got)
+	}
+	got := strings.Fields(out.String())
+	slices.Sort(got)
+	if want := []string{"A", "B", "C"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
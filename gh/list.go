@@ -0,0 +1,327 @@
+package gh
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/savioxavier/termlink"
+)
+
+// Output formats of the list action.
+const (
+	FormatText     = "text"
+	FormatJSON     = "json"
+	FormatNDJSON   = "ndjson"
+	FormatCSV      = "csv"
+	FormatTSV      = "tsv"
+	FormatMarkdown = "markdown"
+)
+
+// Formats is the list of supported output formats of the list action.
+var Formats = []string{FormatText, FormatJSON, FormatNDJSON, FormatCSV, FormatTSV, FormatMarkdown}
+
+// lister writes notifications matched by the list action.
+type lister interface {
+	add(m map[string]any) error
+	flush() error
+}
+
+func newLister(format, tmpl string, w io.Writer) (lister, error) {
+	if tmpl != "" {
+		if format != "" && format != FormatText {
+			return nil, fmt.Errorf("template can not be used with format: %s", format)
+		}
+		t, err := template.New("list").Funcs(templateFuncs).Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid list template: %w", err)
+		}
+		return &templateLister{w: w, tmpl: t}, nil
+	}
+	switch format {
+	case "", FormatText:
+		return &textLister{w: w}, nil
+	case FormatJSON:
+		return &jsonLister{w: w}, nil
+	case FormatNDJSON:
+		return &ndjsonLister{enc: newJSONEncoder(w)}, nil
+	case FormatCSV:
+		return &delimitedLister{w: csv.NewWriter(w)}, nil
+	case FormatTSV:
+		cw := csv.NewWriter(w)
+		cw.Comma = '\t'
+		return &delimitedLister{w: cw}, nil
+	case FormatMarkdown:
+		return &markdownLister{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format: %s (supported: %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// textLister writes colored two-line text with terminal hyperlinks.
+type textLister struct {
+	w io.Writer
+}
+
+func (l *textLister) add(m map[string]any) error {
+	number := stateMark(m) + numberC.Sprint(" "+ref(m)) + " " + statusMark(m)
+	if _, err := fmt.Fprintf(l.w, "%s\n", number); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(l.w, "  %s\n", titleLink(m)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (l *textLister) flush() error {
+	return nil
+}
+
+// templateLister renders each notification through a Go text/template.
+type templateLister struct {
+	w    io.Writer
+	tmpl *template.Template
+}
+
+func (l *templateLister) add(m map[string]any) error {
+	buf := new(strings.Builder)
+	if err := l.tmpl.Execute(buf, m); err != nil {
+		return fmt.Errorf("failed to render list template: %w", err)
+	}
+	out := buf.String()
+	if !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	_, err := io.WriteString(l.w, out)
+	return err
+}
+
+func (l *templateLister) flush() error {
+	return nil
+}
+
+// jsonLister writes all notifications as a JSON array when flushed.
+type jsonLister struct {
+	w     io.Writer
+	items []map[string]any
+}
+
+func (l *jsonLister) add(m map[string]any) error {
+	// The fields are copied, as later actions of the rules update them.
+	l.items = append(l.items, jsonFields(m))
+	return nil
+}
+
+func (l *jsonLister) flush() error {
+	items := l.items
+	if items == nil {
+		items = []map[string]any{}
+	}
+	l.items = nil
+	enc := newJSONEncoder(l.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(items)
+}
+
+// ndjsonLister writes each notification as a line of JSON.
+type ndjsonLister struct {
+	enc *json.Encoder
+}
+
+func (l *ndjsonLister) add(m map[string]any) error {
+	return l.enc.Encode(jsonFields(m))
+}
+
+func (l *ndjsonLister) flush() error {
+	return nil
+}
+
+// delimitedLister writes notifications as CSV or TSV when flushed,
+// with a header row of the fields of all notifications, as subjects of different types have different fields.
+type delimitedLister struct {
+	w     *csv.Writer
+	items []map[string]any
+}
+
+func (l *delimitedLister) add(m map[string]any) error {
+	l.items = append(l.items, maps.Clone(m))
+	return nil
+}
+
+func (l *delimitedLister) flush() error {
+	items := l.items
+	l.items = nil
+	if len(items) == 0 {
+		return nil
+	}
+	keys := fieldNames(items...)
+	if err := l.w.Write(keys); err != nil {
+		return err
+	}
+	for _, m := range items {
+		if err := l.w.Write(lo.Map(keys, func(k string, _ int) string {
+			return formatCell(m[k])
+		})); err != nil {
+			return err
+		}
+	}
+	l.w.Flush()
+	return l.w.Error()
+}
+
+// markdownLister writes notifications as a Markdown table when flushed, with the columns of the fields of all notifications.
+type markdownLister struct {
+	w     io.Writer
+	items []map[string]any
+}
+
+func (l *markdownLister) add(m map[string]any) error {
+	l.items = append(l.items, maps.Clone(m))
+	return nil
+}
+
+func (l *markdownLister) flush() error {
+	items := l.items
+	l.items = nil
+	if len(items) == 0 {
+		return nil
+	}
+	keys := fieldNames(items...)
+	if _, err := fmt.Fprintf(l.w, "| %s |\n", strings.Join(keys, " | ")); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(l.w, "|%s\n", strings.Repeat(" --- |", len(keys))); err != nil {
+		return err
+	}
+	for _, m := range items {
+		cells := lo.Map(keys, func(k string, _ int) string {
+			return strings.NewReplacer("|", `\|`, "\n", " ").Replace(formatCell(m[k]))
+		})
+		if _, err := fmt.Fprintf(l.w, "| %s |\n", strings.Join(cells, " | ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateFuncs are the functions available in the list template.
+var templateFuncs = template.FuncMap{
+	"title":      titleC.Sprint,
+	"number":     numberC.Sprint,
+	"open":       openC.Sprint,
+	"merged":     mergedC.Sprint,
+	"closed":     closedC.Sprint,
+	"draft":      draftC.Sprint,
+	"passed":     passedC.Sprint,
+	"inProgress": inProgressC.Sprint,
+	"failed":     failedC.Sprint,
+	"stateMark":  stateMark,
+	"statusMark": statusMark,
+	"link":       link,
+	"join":       func(sep string, s []string) string { return strings.Join(s, sep) },
+}
+
+// stateMark returns the mark colored by the state of the issue/pull request/discussion.
+func stateMark(m map[string]any) string {
+	mark := "▬"
+	switch {
+	case m["state"] == "open":
+		if draft, ok := m["draft"].(bool); ok && draft {
+			return draftC.Sprint(mark)
+		}
+		return openC.Sprint(mark)
+	case m["merged"] == true:
+		return mergedC.Sprint(mark)
+	case m["state"] == "closed":
+		return closedC.Sprint(mark)
+	}
+	return mark
+}
+
+// statusMark returns the mark colored by the status of checks, or empty if there are no checks.
+func statusMark(m map[string]any) string {
+	mark := "●"
+	if passed, ok := m["passed"].(bool); ok && passed {
+		return passedC.Sprint(mark)
+	} else if inProgress, ok := m["in_progress"].(bool); ok && inProgress {
+		return inProgressC.Sprint(mark)
+	} else if failed, ok := m["failed"].(bool); ok && failed {
+		return failedC.Sprint(mark)
+	}
+	return ""
+}
+
+// ref returns the reference of the subject such as owner/repo #number.
+func ref(m map[string]any) string {
+	r := fmt.Sprintf("%s/%s", m["owner"], m["repo"])
+	if n, ok := m["number"].(int); ok && n > 0 {
+		r += fmt.Sprintf(" #%d", n)
+	}
+	return r
+}
+
+// titleLink returns the title linked to the HTML URL.
+func titleLink(m map[string]any) string {
+	htmlURL, _ := m["html_url"].(string)
+	return link(titleC.Sprint(m["title"]), htmlURL)
+}
+
+// link returns text with a terminal hyperlink to url, or text followed by url if hyperlinks are not supported.
+func link(text, url string) string {
+	if termlink.SupportsHyperlinks() {
+		return termlink.Link(text, url)
+	}
+	return fmt.Sprintf("%s ( %s )", text, url)
+}
+
+func newJSONEncoder(w io.Writer) *json.Encoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc
+}
+
+// jsonFields returns a copy of the fields in which zero times are null,
+// as the other formats leave them empty.
+func jsonFields(m map[string]any) map[string]any {
+	fields := maps.Clone(m)
+	for k, v := range fields {
+		if t, ok := v.(time.Time); ok && t.IsZero() {
+			fields[k] = nil
+		}
+	}
+	return fields
+}
+
+// fieldNames returns the sorted names of the fields of all maps.
+func fieldNames(ms ...map[string]any) []string {
+	keys := lo.Keys(lo.Assign(ms...))
+	slices.Sort(keys)
+	return keys
+}
+
+// formatCell formats a field value for tabular output.
+func formatCell(v any) string {
+	switch vv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return vv
+	case []string:
+		return strings.Join(vv, ",")
+	case time.Time:
+		if vv.IsZero() {
+			return ""
+		}
+		return vv.Format(time.RFC3339)
+	default:
+		return fmt.Sprintf("%v", vv)
+	}
+}
@@ -0,0 +1,225 @@
+package gh
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/k1LoW/gh-triage/profile"
+)
+
+func TestLister(t *testing.T) {
+	items := []map[string]any{
+		{"number": 1, "title": "Fix bug", "labels": []string{"bug", "p1"}, "merged": true, "merged_at": time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"number": 2, "title": "Use | in title", "labels": []string{}, "merged": false, "merged_at": time.Time{}},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatJSON, `[
+  {
+    "labels": [
+      "bug",
+      "p1"
+    ],
+    "merged": true,
+    "merged_at": "2025-01-02T00:00:00Z",
+    "number": 1,
+    "title": "Fix bug"
+  },
+  {
+    "labels": [],
+    "merged": false,
+    "merged_at": null,
+    "number": 2,
+    "title": "Use | in title"
+  }
+]
+`},
+		{FormatNDJSON, `{"labels":["bug","p1"],"merged":true,"merged_at":"2025-01-02T00:00:00Z","number":1,"title":"Fix bug"}
+{"labels":[],"merged":false,"merged_at":null,"number":2,"title":"Use | in title"}
+`},
+		{FormatCSV, `labels,merged,merged_at,number,title
+"bug,p1",true,2025-01-02T00:00:00Z,1,Fix bug
+,false,,2,Use | in title
+`},
+		{FormatTSV, "labels\tmerged\tmerged_at\tnumber\ttitle\nbug,p1\ttrue\t2025-01-02T00:00:00Z\t1\tFix bug\n\tfalse\t\t2\tUse | in title\n"},
+		{FormatMarkdown, `| labels | merged | merged_at | number | title |
+| --- | --- | --- | --- | --- |
+| bug,p1 | true | 2025-01-02T00:00:00Z | 1 | Fix bug |
+|  | false |  | 2 | Use \| in title |
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			l, err := newLister(tt.format, "", buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, m := range items {
+				if err := l.add(m); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := l.flush(); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListerMixedSubjects(t *testing.T) {
+	// Subjects of different types have different fields, and the columns come from all of them.
+	items := []map[string]any{
+		{"subject_type": "Release", "title": "v1.0.0", "tag_name": "v1.0.0"},
+		{"subject_type": "PullRequest", "title": "Fix bug", "number": 1, "draft": true},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatCSV, `draft,number,subject_type,tag_name,title
+,,Release,v1.0.0,v1.0.0
+true,1,PullRequest,,Fix bug
+`},
+		{FormatMarkdown, `| draft | number | subject_type | tag_name | title |
+| --- | --- | --- | --- | --- |
+|  |  | Release | v1.0.0 | v1.0.0 |
+| true | 1 | PullRequest |  | Fix bug |
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			l, err := newLister(tt.format, "", buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, m := range items {
+				if err := l.add(m); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := l.flush(); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListerCopiesFields(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatCSV, FormatMarkdown} {
+		t.Run(format, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			l, err := newLister(format, "", buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := map[string]any{"unread": true}
+			if err := l.add(m); err != nil {
+				t.Fatal(err)
+			}
+			// A later action such as read updates the fields before the list is flushed.
+			m["unread"] = false
+			if err := l.flush(); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); !strings.Contains(got, "true") || strings.Contains(got, "false") {
+				t.Errorf("Expected the fields at the time of the list action, got %s", got)
+			}
+		})
+	}
+}
+
+func TestNewListerUnsupported(t *testing.T) {
+	if _, err := newLister("xml", "", new(bytes.Buffer)); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+func TestTemplateLister(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		tmpl   string
+		want   string
+	}{
+		{"fields", "", `{{.owner}}/{{.repo}}#{{.number}} {{.author}} [{{join "," .labels}}]`, "k1LoW/gh-triage#1 k1LoW [bug,p1]\n"},
+		{"trailing newline", FormatText, "{{.title | title}}\n", "Fix bug\n"},
+		{"helpers", "", `{{stateMark .}} {{if .merged}}{{merged "merged"}}{{end}}`, "▬ merged\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			l, err := newLister(tt.format, tt.tmpl, buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := l.add(map[string]any{
+				"owner":  "k1LoW",
+				"repo":   "gh-triage",
+				"number": 1,
+				"title":  "Fix bug",
+				"author": "k1LoW",
+				"labels": []string{"bug", "p1"},
+				"merged": true,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		template string
+		wantTmpl string
+		want     string
+	}{
+		{"profile template", "", "", "{{ .title }}", "Fix bug\n"},
+		{"profile template with text format", FormatText, "", "{{ .title }}", "Fix bug\n"},
+		{"format overrides profile template", FormatNDJSON, "", "", `{"title":"Fix bug"}` + "\n"},
+		{"template option overrides profile template", "", "[{{ .title }}]", "[{{ .title }}]", "[Fix bug]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &profile.Profile{Rules: []profile.Rule{{Conditions: []string{"*"}, Actions: []profile.RuleAction{{Type: profile.ActionList, Template: "{{ .title }}"}}}}}
+			buf := new(bytes.Buffer)
+			c, err := newClient(cfg, github.NewClient(nil), buf, false, WithFormat(tt.format), WithTemplate(tt.template))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tmpl := c.listTemplate(c.rules[0].Actions[0])
+			if tmpl != tt.wantTmpl {
+				t.Errorf("got template %q, want %q", tmpl, tt.wantTmpl)
+			}
+			if err := c.listers[tmpl].add(map[string]any{"title": "Fix bug"}); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewListerTemplateWithFormat(t *testing.T) {
+	if _, err := newLister(FormatJSON, "{{.title}}", new(bytes.Buffer)); err == nil {
+		t.Error("expected error when template is used with a non-text format")
+	}
+}
@@ -0,0 +1,61 @@
+package gh
+
+import (
+	"github.com/k1LoW/gh-triage/cond"
+	"github.com/k1LoW/gh-triage/profile"
+	"github.com/samber/lo"
+)
+
+var (
+	// reviewFields are the fields that need the reviews of a pull request.
+	reviewFields = []string{"approved", "review_states"}
+	// statusFields are the fields that need the commit statuses and check runs of a pull request.
+	statusFields = []string{"status_passed", "checks_passed", "passed", "failed", "in_progress"}
+)
+
+// fieldNeeds tells which of the fields that need extra API calls have to be collected.
+type fieldNeeds struct {
+	reviews  bool
+	statuses bool
+}
+
+var allNeeds = fieldNeeds{reviews: true, statuses: true}
+
+// resolveNeeds determines the fields needed by the conditions and the actions of the rules,
+// so that the API calls for fields nobody uses are skipped.
+func (c *Client) resolveNeeds() fieldNeeds {
+	var needs fieldNeeds
+	var conds []string
+	for _, r := range c.rules {
+		conds = append(conds, r.Conditions...)
+		for _, a := range r.Actions {
+			switch a.Type {
+			case profile.ActionList:
+				if c.listTemplate(a) != "" || (c.format != "" && c.format != FormatText) {
+					// Templates and the other formats can output every field.
+					return allNeeds
+				}
+				// The text format shows the status mark.
+				needs.statuses = true
+			case profile.ActionExec:
+				return allNeeds
+			case profile.ActionWebhook:
+				if a.Template != "" || a.Payload == "" || a.Payload == profile.PayloadJSON {
+					return allNeeds
+				}
+			case profile.ActionNotify:
+				if a.Template != "" {
+					return allNeeds
+				}
+			}
+		}
+	}
+	fields, err := cond.Fields(conds)
+	if err != nil {
+		// The invalid condition is reported when it is evaluated.
+		return allNeeds
+	}
+	needs.reviews = needs.reviews || lo.Some(fields, reviewFields)
+	needs.statuses = needs.statuses || lo.Some(fields, statusFields)
+	return needs
+}
@@ -0,0 +1,41 @@
+package gh
+
+import (
+	"io"
+	"testing"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/k1LoW/gh-triage/profile"
+)
+
+func TestResolveNeeds(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   profile.Rule
+		format string
+		want   fieldNeeds
+	}{
+		{"notification fields only", profile.Rule{Conditions: []string{"repo == 'gh-triage' && reason == 'mention'"}, Actions: []profile.RuleAction{{Type: profile.ActionDone}}}, "", fieldNeeds{}},
+		{"reviews", profile.Rule{Conditions: []string{"is_pull_request && !approved"}, Actions: []profile.RuleAction{{Type: profile.ActionRead}}}, "", fieldNeeds{reviews: true}},
+		{"statuses", profile.Rule{Conditions: []string{"merged", "failed"}, Actions: []profile.RuleAction{{Type: profile.ActionDone}}}, "", fieldNeeds{statuses: true}},
+		{"text list", profile.Rule{Conditions: []string{"*"}, Actions: []profile.RuleAction{{Type: profile.ActionList}}}, "", fieldNeeds{statuses: true}},
+		{"json list", profile.Rule{Conditions: []string{"*"}, Actions: []profile.RuleAction{{Type: profile.ActionList}}}, FormatJSON, allNeeds},
+		{"list template", profile.Rule{Conditions: []string{"*"}, Actions: []profile.RuleAction{{Type: profile.ActionList, Template: "{{ .title }}"}}}, "", allNeeds},
+		{"slack webhook", profile.Rule{Conditions: []string{"*"}, Actions: []profile.RuleAction{{Type: profile.ActionWebhook, URL: "https://example.com", Payload: profile.PayloadSlack}}}, "", fieldNeeds{}},
+		{"json webhook", profile.Rule{Conditions: []string{"*"}, Actions: []profile.RuleAction{{Type: profile.ActionWebhook, URL: "https://example.com"}}}, "", allNeeds},
+		{"exec", profile.Rule{Conditions: []string{"*"}, Actions: []profile.RuleAction{{Type: profile.ActionExec, Command: "true"}}}, "", allNeeds},
+		{"invalid condition", profile.Rule{Conditions: []string{"merged &&"}, Actions: []profile.RuleAction{{Type: profile.ActionDone}}}, "", allNeeds},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &profile.Profile{Rules: []profile.Rule{tt.rule}}
+			c, err := newClient(cfg, github.NewClient(nil), io.Discard, false, WithFormat(tt.format))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.needs != tt.want {
+				t.Errorf("got %+v, want %+v", c.needs, tt.want)
+			}
+		})
+	}
+}
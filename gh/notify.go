@@ -0,0 +1,75 @@
+package gh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/k1LoW/gh-triage/profile"
+)
+
+// Notifier raises desktop notifications.
+type Notifier interface {
+	Notify(ctx context.Context, title, body, url string) error
+}
+
+// commandNotifier raises desktop notifications by running the command of the platform.
+type commandNotifier struct{}
+
+func (commandNotifier) Notify(ctx context.Context, title, body, _ string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		// notify-send sends the notification to the notification daemon via D-Bus.
+		cmd = exec.CommandContext(ctx, "notify-send", "--app-name=gh-triage", title, body)
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, body)
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to notify: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// notify raises a desktop notification for the notification thread.
+// A thread is announced only once per action until it is updated, so that it is not announced on every tick of watch mode.
+func (c *Client) notify(ctx context.Context, a *profile.RuleAction, m map[string]any) error {
+	threadID, _ := m["thread_id"].(string)
+	updatedAt, _ := m["updated_at"].(time.Time)
+	if last, ok := c.notified[a][threadID]; ok && !updatedAt.After(last) {
+		if c.verbose {
+			slog.Info("Already notified, skipping", "subject", ref(m))
+		}
+		return errSkipped
+	}
+	title := ref(m)
+	body, _ := m["title"].(string)
+	if tmpl, ok := c.actionTmpls[a]; ok {
+		buf := new(bytes.Buffer)
+		if err := tmpl.Execute(buf, m); err != nil {
+			return fmt.Errorf("failed to render notify template: %w", err)
+		}
+		body = buf.String()
+	}
+	htmlURL, _ := m["html_url"].(string)
+	if err := c.notifier.Notify(ctx, title, body, htmlURL); err != nil {
+		return err
+	}
+	if c.notified[a] == nil {
+		c.notified[a] = map[string]time.Time{}
+	}
+	c.notified[a][threadID] = updatedAt
+	return nil
+}
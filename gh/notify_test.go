@@ -0,0 +1,93 @@
+package gh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/k1LoW/gh-triage/profile"
+)
+
+type recordNotifier struct {
+	titles []string
+	bodies []string
+}
+
+func (n *recordNotifier) Notify(_ context.Context, title, body, _ string) error {
+	n.titles = append(n.titles, title)
+	n.bodies = append(n.bodies, body)
+	return nil
+}
+
+func TestNotify(t *testing.T) {
+	n := &recordNotifier{}
+	a := &profile.RuleAction{Type: profile.ActionNotify, Template: "{{.title}} by {{.author}}"}
+	c := &Client{
+		notifier:    n,
+		actionTmpls: map[*profile.RuleAction]*template.Template{},
+		notified:    map[*profile.RuleAction]map[string]time.Time{},
+	}
+	if err := c.addActionTemplate(a); err != nil {
+		t.Fatal(err)
+	}
+	updatedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := map[string]any{
+		"thread_id":  "1",
+		"owner":      "k1LoW",
+		"repo":       "gh-triage",
+		"number":     1,
+		"title":      "Fix bug",
+		"author":     "alice",
+		"updated_at": updatedAt,
+	}
+	// The same thread is announced only once until it is updated, as in ticks of watch mode.
+	if err := c.notify(context.Background(), a, m); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.notify(context.Background(), a, m); !errors.Is(err, errSkipped) {
+		t.Fatalf("got %v, want %v", err, errSkipped)
+	}
+	m["updated_at"] = updatedAt.Add(time.Hour)
+	if err := c.notify(context.Background(), a, m); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.titles) != 2 {
+		t.Fatalf("got %d notifications, want 2", len(n.titles))
+	}
+	if n.titles[0] != "k1LoW/gh-triage #1" || n.bodies[0] != "Fix bug by alice" {
+		t.Errorf("got (%q, %q)", n.titles[0], n.bodies[0])
+	}
+}
+
+func TestTriageNotifyDedup(t *testing.T) {
+	notifications := `[
+  {"id": "1", "unread": true, "updated_at": "2025-01-01T00:00:00Z", "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "A", "url": "https://api.github.com/repos/owner/repo/issues/1", "type": "Issue"}},
+  {"id": "2", "unread": true, "updated_at": "2025-01-01T00:00:00Z", "repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"title": "B", "url": "https://api.github.com/repos/owner/repo/issues/2", "type": "Issue"}}
+]`
+	ts := newFakeGitHub(t, notifications, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"number":%s,"state":"open"}`, path.Base(r.URL.Path))
+	})
+	defer ts.Close()
+	n := &recordNotifier{}
+	cfg := &profile.Profile{
+		Rules:       []profile.Rule{{Max: 1, Conditions: []string{"open"}, Actions: []profile.RuleAction{{Type: profile.ActionNotify}}}},
+		Concurrency: 1,
+	}
+	c := newTestClient(t, cfg, ts, io.Discard, WithNotifier(n))
+	// The thread already announced on the first tick does not use up max on the second tick.
+	for range 2 {
+		if err := c.Triage(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"A", "B"}; !slices.Equal(n.bodies, want) {
+		t.Errorf("got %v, want %v", n.bodies, want)
+	}
+}
@@ -0,0 +1,140 @@
+package gh
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+)
+
+const (
+	// defaultConcurrency is the default number of notifications processed at the same time.
+	defaultConcurrency = 8
+	// maxRateLimitRetries is the maximum number of retries of a request that hit the rate limit.
+	maxRateLimitRetries = 3
+	// secondaryRateLimitWait is the time to wait when the secondary rate limit is hit without Retry-After.
+	secondaryRateLimitWait = time.Minute
+	// callsPerNotification is the estimated number of REST calls to collect the fields of a notification.
+	callsPerNotification = 5
+)
+
+// sleep waits for the duration or until the context is done. It is replaced in tests.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// rateLimitTransport retries requests that hit the primary or secondary rate limit,
+// waiting as long as Retry-After or X-RateLimit-Reset tells.
+type rateLimitTransport struct {
+	base http.RoundTripper
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for i := 0; ; i++ {
+		r := req
+		if i > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		res, err := t.base.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		wait, limited := rateLimitWait(res, time.Now())
+		// Requests whose body can not be read again are not retried.
+		replayable := req.Body == nil || req.GetBody != nil
+		if !limited || !replayable || i >= maxRateLimitRetries {
+			return res, nil
+		}
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+		slog.Warn("Hit the rate limit, waiting to retry", "status", res.StatusCode, "url", req.URL.Path, "wait", wait)
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// rateLimitWait reports whether the response hit the rate limit, and how long to wait before retrying.
+func rateLimitWait(res *http.Response, now time.Time) (time.Duration, bool) {
+	if res.StatusCode != http.StatusForbidden && res.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+		return time.Duration(max(s, 1)) * time.Second, true
+	}
+	if res.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			// Wait a second more than the reset time to absorb clock skew.
+			return max(time.Unix(reset, 0).Sub(now), 0) + time.Second, true
+		}
+	}
+	if res.StatusCode == http.StatusTooManyRequests || isSecondaryRateLimit(res) {
+		return secondaryRateLimitWait, true
+	}
+	// 403 without rate limit headers is a permission error.
+	return 0, false
+}
+
+// isSecondaryRateLimit reports whether the error message of the response tells the secondary rate limit.
+// The body is restored so that the caller can read it.
+func isSecondaryRateLimit(res *http.Response) bool {
+	if res.Body == nil {
+		return false
+	}
+	b, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	res.Body = io.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(b)), "secondary rate limit")
+}
+
+// waitRateLimit pauses until the rate limit is reset if the remaining quota is not enough for the calls.
+// It only logs a warning if the rate limit can not be checked.
+func (c *Client) waitRateLimit(ctx context.Context, calls int) error {
+	limits, _, err := c.client.RateLimit.Get(ctx)
+	if err != nil {
+		slog.Warn("Failed to check the rate limit", "error", err)
+		return nil
+	}
+	core := limits.GetCore()
+	if core == nil || core.Remaining >= calls {
+		return nil
+	}
+	wait := max(time.Until(core.Reset.Time), 0) + time.Second
+	slog.Warn("Remaining rate limit is not enough, pausing until it is reset", "remaining", core.Remaining, "needed", calls, "reset", core.Reset.Time, "wait", wait)
+	return sleep(ctx, wait)
+}
+
+// withTransport returns a copy of the client whose transport is wrapped.
+func withTransport(client *github.Client, wrap func(http.RoundTripper) http.RoundTripper) *github.Client {
+	hc := client.Client()
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	hc.Transport = wrap(base)
+	wrapped := github.NewClient(hc)
+	wrapped.BaseURL = client.BaseURL
+	wrapped.UploadURL = client.UploadURL
+	return wrapped
+}
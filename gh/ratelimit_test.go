@@ -0,0 +1,136 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+)
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Date(2025, 8, 4, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		status      int
+		header      map[string]string
+		body        string
+		wantWait    time.Duration
+		wantLimited bool
+	}{
+		{"ok", http.StatusOK, nil, "", 0, false},
+		{"secondary rate limit with Retry-After", http.StatusForbidden, map[string]string{"Retry-After": "30"}, "", 30 * time.Second, true},
+		{"primary rate limit", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(now.Add(5*time.Minute).Unix(), 10)}, "", 5*time.Minute + time.Second, true},
+		{"too many requests without headers", http.StatusTooManyRequests, nil, "", secondaryRateLimitWait, true},
+		{"secondary rate limit without headers", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "4999"}, `{"message":"You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`, secondaryRateLimitWait, true},
+		{"permission error", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "4999"}, `{"message":"Resource not accessible by integration"}`, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{StatusCode: tt.status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(tt.body))}
+			for k, v := range tt.header {
+				res.Header.Set(k, v)
+			}
+			wait, limited := rateLimitWait(res, now)
+			if wait != tt.wantWait || limited != tt.wantLimited {
+				t.Errorf("got (%s, %v), want (%s, %v)", wait, limited, tt.wantWait, tt.wantLimited)
+			}
+			// The body is left for the caller.
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.body {
+				t.Errorf("got body %q, want %q", b, tt.body)
+			}
+		})
+	}
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	var waits []time.Duration
+	orig := sleep
+	t.Cleanup(func() {
+		sleep = orig
+	})
+	sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		bodies = append(bodies, string(b))
+		if len(bodies) < 3 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	hc := &http.Client{Transport: &rateLimitTransport{base: http.DefaultTransport}}
+	res, err := hc.Post(ts.URL, "application/json", strings.NewReader(`{"body":"hello"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("got status %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	if len(waits) != 2 || waits[0] != 2*time.Second {
+		t.Errorf("got waits %v", waits)
+	}
+	for _, b := range bodies {
+		if b != `{"body":"hello"}` {
+			t.Errorf("body is not replayed: %q", b)
+		}
+	}
+}
+
+func TestWaitRateLimit(t *testing.T) {
+	var waits []time.Duration
+	orig := sleep
+	t.Cleanup(func() {
+		sleep = orig
+	})
+	sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	reset := time.Now().Add(10 * time.Minute).Unix()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"resources":{"core":{"limit":5000,"remaining":100,"reset":%d}}}`, reset)
+	}))
+	defer ts.Close()
+	client := github.NewClient(ts.Client())
+	u, err := url.Parse(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.BaseURL = u
+	c := &Client{client: client}
+
+	if err := c.waitRateLimit(context.Background(), 100); err != nil {
+		t.Fatal(err)
+	}
+	if len(waits) != 0 {
+		t.Errorf("Expected no wait, got %v", waits)
+	}
+	if err := c.waitRateLimit(context.Background(), 500); err != nil {
+		t.Fatal(err)
+	}
+	if len(waits) != 1 || waits[0] < 9*time.Minute || waits[0] > 11*time.Minute {
+		t.Errorf("Expected to wait until reset, got %v", waits)
+	}
+}
@@ -0,0 +1,203 @@
+package gh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/k1LoW/gh-triage/profile"
+	"github.com/samber/lo"
+	"github.com/shurcooL/githubv4"
+)
+
+// discussionIDQuery is the GraphQL query for fetching the node ID of a discussion.
+type discussionIDQuery struct {
+	Repository struct {
+		Discussion struct {
+			ID githubv4.ID
+		} `graphql:"discussion(number: $number)"`
+	} `graphql:"repository(owner: $owner, name: $repo)"`
+}
+
+// labelIDQuery is the GraphQL query for fetching the node ID of a label.
+type labelIDQuery struct {
+	Repository struct {
+		Label *struct {
+			ID githubv4.ID
+		} `graphql:"label(name: $name)"`
+	} `graphql:"repository(owner: $owner, name: $repo)"`
+}
+
+// editSubject performs the action on the issue, pull request or discussion behind the notification.
+func (c *Client) editSubject(ctx context.Context, a profile.RuleAction, m map[string]any) error {
+	owner, _ := m["owner"].(string)
+	repo, _ := m["repo"].(string)
+	number, _ := m["number"].(int)
+	isDiscussion, _ := m["is_discussion"].(bool)
+	if !editable(a.Type, m) {
+		if c.verbose {
+			slog.Warn("Action is not supported for the subject type, skipping", "action", a.Type, "type", m["subject_type"], "owner", owner, "repo", repo, "number", number)
+		}
+		return nil
+	}
+	labels, _ := m["labels"].([]string)
+
+	switch a.Type {
+	case profile.ActionLabel:
+		if isDiscussion {
+			if err := c.labelDiscussion(ctx, owner, repo, number, a.Labels, true); err != nil {
+				return err
+			}
+		} else if _, _, err := c.client.Issues.AddLabelsToIssue(ctx, owner, repo, number, a.Labels); err != nil {
+			return fmt.Errorf("failed to add labels: %w", err)
+		}
+		m["labels"] = lo.Union(labels, a.Labels)
+	case profile.ActionUnlabel:
+		if isDiscussion {
+			if err := c.labelDiscussion(ctx, owner, repo, number, a.Labels, false); err != nil {
+				return err
+			}
+		} else {
+			for _, l := range a.Labels {
+				if !lo.Contains(labels, l) {
+					continue
+				}
+				if _, err := c.client.Issues.RemoveLabelForIssue(ctx, owner, repo, number, l); err != nil {
+					var errResp *github.ErrorResponse
+					if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound {
+						continue
+					}
+					return fmt.Errorf("failed to remove label: %w", err)
+				}
+			}
+		}
+		m["labels"] = lo.Without(labels, a.Labels...)
+	case profile.ActionComment:
+		if isDiscussion {
+			if err := c.commentDiscussion(ctx, owner, repo, number, a.Body); err != nil {
+				return err
+			}
+		} else if _, _, err := c.client.Issues.CreateComment(ctx, owner, repo, number, &github.IssueComment{Body: github.Ptr(a.Body)}); err != nil {
+			return fmt.Errorf("failed to create comment: %w", err)
+		}
+	case profile.ActionAssign:
+		assignees := a.Assignees
+		if len(assignees) == 0 {
+			me, _ := m["me"].(string)
+			assignees = []string{me}
+		}
+		if _, _, err := c.client.Issues.AddAssignees(ctx, owner, repo, number, assignees); err != nil {
+			return fmt.Errorf("failed to add assignees: %w", err)
+		}
+		current, _ := m["assignees"].([]string)
+		m["assignees"] = lo.Union(current, assignees)
+	case profile.ActionRequestReview:
+		if _, _, err := c.client.PullRequests.RequestReviewers(ctx, owner, repo, number, github.ReviewersRequest{
+			Reviewers:     a.Reviewers,
+			TeamReviewers: a.TeamReviewers,
+		}); err != nil {
+			return fmt.Errorf("failed to request reviewers: %w", err)
+		}
+		reviewers, _ := m["reviewers"].([]string)
+		m["reviewers"] = lo.Union(reviewers, a.Reviewers)
+		slugs, _ := m["review_team_slugs"].([]string)
+		m["review_team_slugs"] = lo.Union(slugs, lo.Map(a.TeamReviewers, func(t string, _ int) string {
+			return owner + "/" + t
+		}))
+	default:
+		return fmt.Errorf("unsupported action type: %s", a.Type)
+	}
+	return nil
+}
+
+// editable reports whether the action can be performed on the subject of the notification.
+// Only issues, pull requests and discussions can be edited, discussions can not be assigned,
+// and reviews can only be requested for pull requests.
+func editable(action string, m map[string]any) bool {
+	isIssue, _ := m["is_issue"].(bool)
+	isPullRequest, _ := m["is_pull_request"].(bool)
+	isDiscussion, _ := m["is_discussion"].(bool)
+	switch action {
+	case profile.ActionAssign:
+		return isIssue || isPullRequest
+	case profile.ActionRequestReview:
+		return isPullRequest
+	default:
+		return isIssue || isPullRequest || isDiscussion
+	}
+}
+
+// labelDiscussion adds or removes labels of a discussion.
+func (c *Client) labelDiscussion(ctx context.Context, owner, repo string, number int, labels []string, add bool) error {
+	id, err := c.discussionID(ctx, owner, repo, number)
+	if err != nil {
+		return err
+	}
+	var labelIDs []githubv4.ID
+	for _, l := range labels {
+		var q labelIDQuery
+		if err := c.v4Client.Query(ctx, &q, map[string]any{
+			"owner": githubv4.String(owner),
+			"repo":  githubv4.String(repo),
+			"name":  githubv4.String(l),
+		}); err != nil {
+			return fmt.Errorf("failed to get label: %w", err)
+		}
+		if q.Repository.Label == nil {
+			return fmt.Errorf("label not found: %s", l)
+		}
+		labelIDs = append(labelIDs, q.Repository.Label.ID)
+	}
+	if add {
+		var mu struct {
+			AddLabelsToLabelable struct {
+				ClientMutationID string
+			} `graphql:"addLabelsToLabelable(input: $input)"`
+		}
+		if err := c.v4Client.Mutate(ctx, &mu, githubv4.AddLabelsToLabelableInput{LabelableID: id, LabelIDs: labelIDs}, nil); err != nil {
+			return fmt.Errorf("failed to add labels to discussion: %w", err)
+		}
+		return nil
+	}
+	var mu struct {
+		RemoveLabelsFromLabelable struct {
+			ClientMutationID string
+		} `graphql:"removeLabelsFromLabelable(input: $input)"`
+	}
+	if err := c.v4Client.Mutate(ctx, &mu, githubv4.RemoveLabelsFromLabelableInput{LabelableID: id, LabelIDs: labelIDs}, nil); err != nil {
+		return fmt.Errorf("failed to remove labels from discussion: %w", err)
+	}
+	return nil
+}
+
+// commentDiscussion adds a comment to a discussion.
+func (c *Client) commentDiscussion(ctx context.Context, owner, repo string, number int, body string) error {
+	id, err := c.discussionID(ctx, owner, repo, number)
+	if err != nil {
+		return err
+	}
+	var mu struct {
+		AddDiscussionComment struct {
+			ClientMutationID string
+		} `graphql:"addDiscussionComment(input: $input)"`
+	}
+	if err := c.v4Client.Mutate(ctx, &mu, githubv4.AddDiscussionCommentInput{DiscussionID: id, Body: githubv4.String(body)}, nil); err != nil {
+		return fmt.Errorf("failed to add comment to discussion: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) discussionID(ctx context.Context, owner, repo string, number int) (githubv4.ID, error) {
+	var q discussionIDQuery
+	if err := c.v4Client.Query(ctx, &q, map[string]any{
+		"owner":  githubv4.String(owner),
+		"repo":   githubv4.String(repo),
+		"number": githubv4.Int(int32(number)), //nolint:gosec
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get discussion: %w", err)
+	}
+	return q.Repository.Discussion.ID, nil
+}
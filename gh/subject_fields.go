@@ -0,0 +1,181 @@
+package gh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/samber/lo"
+)
+
+// checkRunState is the status and conclusion of a check run in lower case such as "completed" and "success".
+type checkRunState struct {
+	status     string
+	conclusion string
+}
+
+// issueFields collects the fields of the issue with the REST API.
+// It returns false if the issue should be skipped.
+func (c *Client) issueFields(ctx context.Context, m map[string]any, owner, repo string, number int) (bool, error) {
+	issue, _, err := c.client.Issues.Get(ctx, owner, repo, number)
+	if err != nil {
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound {
+			if c.verbose {
+				slog.Warn("Issue not found, skipping", "owner", owner, "repo", repo, "number", number)
+			}
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get issue: %w", err)
+	}
+	m["state"] = issue.GetState()
+	m["open"] = issue.GetState() == "open"
+	m["closed"] = !issue.GetClosedAt().Equal(github.Timestamp{})
+	m["created_at"] = issue.GetCreatedAt().Time
+	m["closed_at"] = issue.GetClosedAt().Time
+	m["labels"] = lo.Map(issue.Labels, func(l *github.Label, _ int) string {
+		return l.GetName()
+	})
+	m["assignees"] = lo.Map(issue.Assignees, func(a *github.User, _ int) string {
+		return a.GetLogin()
+	})
+	m["author"] = issue.GetUser().GetLogin()
+	m["html_url"] = issue.GetHTMLURL()
+	return true, nil
+}
+
+// pullRequestFields collects the fields of the pull request with the REST API.
+// It returns false if the pull request should be skipped.
+func (c *Client) pullRequestFields(ctx context.Context, m map[string]any, owner, repo string, number int) (bool, error) {
+	pr, _, err := c.client.PullRequests.Get(ctx, owner, repo, number)
+	if err != nil {
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound {
+			if c.verbose {
+				slog.Warn("Pull request not found, skipping", "owner", owner, "repo", repo, "number", number)
+			}
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get pull request: %w", err)
+	}
+	m["state"] = pr.GetState()
+	m["open"] = pr.GetState() == "open"
+	m["draft"] = pr.GetDraft()
+	m["merged"] = pr.GetMerged()
+	m["mergeable"] = pr.GetMergeable()
+	m["mergeable_state"] = pr.GetMergeableState()
+	m["closed"] = !pr.GetClosedAt().Equal(github.Timestamp{})
+	m["created_at"] = pr.GetCreatedAt().Time
+	m["closed_at"] = pr.GetClosedAt().Time
+	m["merged_at"] = pr.GetMergedAt().Time
+	m["labels"] = lo.Map(pr.Labels, func(l *github.Label, _ int) string {
+		return l.GetName()
+	})
+	m["reviewers"] = lo.Map(pr.RequestedReviewers, func(r *github.User, _ int) string {
+		return r.GetLogin()
+	})
+	m["review_teams"] = lo.Map(pr.RequestedTeams, func(t *github.Team, _ int) string {
+		return t.GetName()
+	})
+	m["review_team_slugs"] = lo.Map(pr.RequestedTeams, func(t *github.Team, _ int) string {
+		return owner + "/" + t.GetSlug()
+	})
+	m["assignees"] = lo.Map(pr.Assignees, func(a *github.User, _ int) string {
+		return a.GetLogin()
+	})
+	m["author"] = pr.GetUser().GetLogin()
+	m["html_url"] = pr.GetHTMLURL()
+	if c.needs.reviews {
+		reviews, _, err := c.client.PullRequests.ListReviews(ctx, owner, repo, number, &github.ListOptions{})
+		if err != nil {
+			return false, fmt.Errorf("failed to list pull request reviews: %w", err)
+		}
+		slices.SortFunc(reviews, func(a, b *github.PullRequestReview) int {
+			return a.GetSubmittedAt().Compare(b.GetSubmittedAt().Time)
+		})
+		setReviews(m, lo.Map(reviews, func(r *github.PullRequestReview, _ int) string {
+			return r.GetState()
+		}))
+	}
+	if !c.needs.statuses {
+		return true, nil
+	}
+	commitSHA := pr.GetHead().GetSHA()
+
+	combinedStatus, _, err := c.client.Repositories.GetCombinedStatus(ctx, owner, repo, commitSHA, &github.ListOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get combined status: %w", err)
+	}
+	checkRuns, _, err := c.client.Checks.ListCheckRunsForRef(ctx, owner, repo, commitSHA, &github.ListCheckRunsOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to list check runs: %w", err)
+	}
+	setStatuses(m, lo.Map(combinedStatus.Statuses, func(s *github.RepoStatus, _ int) string {
+		return s.GetState()
+	}), lo.Map(checkRuns.CheckRuns, func(r *github.CheckRun, _ int) checkRunState {
+		return checkRunState{status: r.GetStatus(), conclusion: r.GetConclusion()}
+	}))
+	return true, nil
+}
+
+// setReviews sets the review fields from the states of reviews in the order they were submitted.
+func setReviews(m map[string]any, states []string) {
+	m["approved"] = false
+	var reviewStates []string
+	for _, state := range states {
+		reviewStates = append(reviewStates, state)
+		if state == "APPROVED" {
+			m["approved"] = true
+			break
+		}
+	}
+	m["review_states"] = reviewStates
+}
+
+// setStatuses sets the status fields from the states of commit statuses and check runs in lower case.
+func setStatuses(m map[string]any, statuses []string, checkRuns []checkRunState) {
+	statusPassed := true
+	statusFailed := false
+	statusInProgress := false
+L:
+	for _, status := range statuses {
+		switch status {
+		case "success":
+			continue
+		case "failure", "starup_failure":
+			statusPassed = false
+			statusFailed = true
+			break L
+		default:
+			statusPassed = false
+			statusInProgress = true
+		}
+	}
+	checksPassed := true
+	checksFailed := false
+	checksInProgress := false
+LL:
+	for _, checkRun := range checkRuns {
+		switch {
+		case checkRun.status == "completed" && slices.Contains([]string{"neutral", "skipped", "success"}, checkRun.conclusion):
+			continue
+		case slices.Contains([]string{"failure", "startup_failure"}, checkRun.status) || slices.Contains([]string{"canceled", "failure", "stale", "timed_out"}, checkRun.conclusion):
+			checksPassed = false
+			checksFailed = true
+			checksInProgress = false
+			break LL
+		case slices.Contains([]string{"expected", "in_progress", "pending", "queued", "requested", "waiting"}, checkRun.status):
+			checksPassed = false
+			checksInProgress = true
+		}
+	}
+	m["status_passed"] = statusPassed
+	m["checks_passed"] = checksPassed
+	m["passed"] = statusPassed && checksPassed
+	m["failed"] = statusFailed || checksFailed
+	m["in_progress"] = statusInProgress || checksInProgress
+}
@@ -0,0 +1,232 @@
+package gh
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/k1LoW/gh-triage/profile"
+)
+
+func TestEditable(t *testing.T) {
+	issue := map[string]any{"is_issue": true}
+	pr := map[string]any{"is_pull_request": true}
+	discussion := map[string]any{"is_discussion": true}
+	release := map[string]any{"subject_type": "Release"}
+	tests := []struct {
+		action string
+		m      map[string]any
+		want   bool
+	}{
+		{profile.ActionLabel, issue, true},
+		{profile.ActionLabel, pr, true},
+		{profile.ActionLabel, discussion, true},
+		{profile.ActionLabel, release, false},
+		{profile.ActionComment, discussion, true},
+		{profile.ActionComment, release, false},
+		{profile.ActionAssign, issue, true},
+		{profile.ActionAssign, discussion, false},
+		{profile.ActionRequestReview, pr, true},
+		{profile.ActionRequestReview, issue, false},
+	}
+	for _, tt := range tests {
+		if got := editable(tt.action, tt.m); got != tt.want {
+			t.Errorf("editable(%s, %v) = %v, want %v", tt.action, tt.m, got, tt.want)
+		}
+	}
+}
+
+func TestEditSubject(t *testing.T) {
+	fields := func(k string, v any) map[string]any {
+		return map[string]any{"owner": "owner", "repo": "repo", "number": 1, "me": "me", "labels": []string{"triage"}, k: v}
+	}
+	tests := []struct {
+		name       string
+		action     profile.RuleAction
+		m          map[string]any
+		wantReqs   []string
+		wantBodies []string
+		wantField  string
+		want       any
+	}{
+		{
+			"label issue",
+			profile.RuleAction{Type: profile.ActionLabel, Labels: []string{"bug"}},
+			fields("is_issue", true),
+			[]string{"POST /repos/owner/repo/issues/1/labels"},
+			[]string{`["bug"]`},
+			"labels", []string{"triage", "bug"},
+		},
+		{
+			"label pull request",
+			profile.RuleAction{Type: profile.ActionLabel, Labels: []string{"bug"}},
+			fields("is_pull_request", true),
+			[]string{"POST /repos/owner/repo/issues/1/labels"},
+			[]string{`["bug"]`},
+			"labels", []string{"triage", "bug"},
+		},
+		{
+			"unlabel only attached labels",
+			profile.RuleAction{Type: profile.ActionUnlabel, Labels: []string{"triage", "bug"}},
+			fields("is_issue", true),
+			[]string{"DELETE /repos/owner/repo/issues/1/labels/triage"},
+			[]string{""},
+			"labels", []string{},
+		},
+		{
+			"comment",
+			profile.RuleAction{Type: profile.ActionComment, Body: "Thanks!"},
+			fields("is_issue", true),
+			[]string{"POST /repos/owner/repo/issues/1/comments"},
+			[]string{`"body":"Thanks!"`},
+			"", nil,
+		},
+		{
+			"assign me",
+			profile.RuleAction{Type: profile.ActionAssign},
+			fields("is_pull_request", true),
+			[]string{"POST /repos/owner/repo/issues/1/assignees"},
+			[]string{`"assignees":["me"]`},
+			"assignees", []string{"me"},
+		},
+		{
+			"request review",
+			profile.RuleAction{Type: profile.ActionRequestReview, Reviewers: []string{"alice"}, TeamReviewers: []string{"core"}},
+			fields("is_pull_request", true),
+			[]string{"POST /repos/owner/repo/pulls/1/requested_reviewers"},
+			[]string{`"team_reviewers":["core"]`},
+			"review_team_slugs", []string{"owner/core"},
+		},
+		{
+			"label discussion",
+			profile.RuleAction{Type: profile.ActionLabel, Labels: []string{"bug"}},
+			fields("is_discussion", true),
+			[]string{"POST /graphql", "POST /graphql", "POST /graphql"},
+			[]string{`discussion(number: $number)`, `label(name: $name)`, `"labelIds":["L_1"]`},
+			"labels", []string{"triage", "bug"},
+		},
+		{
+			"comment discussion",
+			profile.RuleAction{Type: profile.ActionComment, Body: "Thanks!"},
+			fields("is_discussion", true),
+			[]string{"POST /graphql", "POST /graphql"},
+			[]string{`discussion(number: $number)`, `{"body":"Thanks!","discussionId":"D_1"}`},
+			"", nil,
+		},
+		{
+			"label release",
+			profile.RuleAction{Type: profile.ActionLabel, Labels: []string{"bug"}},
+			fields("subject_type", "Release"),
+			nil, nil,
+			"labels", []string{"triage"},
+		},
+		{
+			"assign discussion",
+			profile.RuleAction{Type: profile.ActionAssign},
+			fields("is_discussion", true),
+			nil, nil,
+			"", nil,
+		},
+		{
+			"request review on issue",
+			profile.RuleAction{Type: profile.ActionRequestReview, Reviewers: []string{"alice"}},
+			fields("is_issue", true),
+			nil, nil,
+			"", nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reqs, bodies []string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Error(err)
+				}
+				reqs = append(reqs, r.Method+" "+r.URL.Path)
+				bodies = append(bodies, string(b))
+				if strings.HasSuffix(r.URL.Path, "/labels") {
+					_, _ = io.WriteString(w, "[]")
+					return
+				}
+				if r.URL.Path != "/graphql" {
+					_, _ = io.WriteString(w, "{}")
+					return
+				}
+				switch body := string(b); {
+				case strings.Contains(body, "discussion(number: $number)"):
+					_, _ = io.WriteString(w, `{"data":{"repository":{"discussion":{"id":"D_1"}}}}`)
+				case strings.Contains(body, "label(name: $name)"):
+					_, _ = io.WriteString(w, `{"data":{"repository":{"label":{"id":"L_1"}}}}`)
+				default:
+					_, _ = io.WriteString(w, `{"data":{}}`)
+				}
+			}))
+			defer ts.Close()
+			c := newTestClient(t, &profile.Profile{}, ts, io.Discard)
+			if err := c.editSubject(context.Background(), tt.action, tt.m); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(reqs, tt.wantReqs) {
+				t.Fatalf("got requests %v, want %v", reqs, tt.wantReqs)
+			}
+			for i, want := range tt.wantBodies {
+				if !strings.Contains(requestText(t, bodies[i]), want) {
+					t.Errorf("got body %s, want containing %s", bodies[i], want)
+				}
+			}
+			if tt.wantField == "" {
+				return
+			}
+			if got := tt.m[tt.wantField]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %s %v, want %v", tt.wantField, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditSubjectLabelNotFound(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if strings.Contains(string(b), "discussion(number: $number)") {
+			_, _ = io.WriteString(w, `{"data":{"repository":{"discussion":{"id":"D_1"}}}}`)
+			return
+		}
+		if strings.Contains(string(b), "mutation") {
+			t.Error("unexpected mutation")
+		}
+		_, _ = io.WriteString(w, `{"data":{"repository":{"label":null}}}`)
+	}))
+	defer ts.Close()
+	c := newTestClient(t, &profile.Profile{}, ts, io.Discard)
+	m := map[string]any{"owner": "owner", "repo": "repo", "number": 1, "is_discussion": true}
+	err := c.editSubject(context.Background(), profile.RuleAction{Type: profile.ActionLabel, Labels: []string{"missing"}}, m)
+	if err == nil || !strings.Contains(err.Error(), "label not found: missing") {
+		t.Errorf("got error %v", err)
+	}
+}
+
+// requestText returns the query and the input of the GraphQL request, or the body as is for the REST API.
+func requestText(t *testing.T, body string) string {
+	t.Helper()
+	var req struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables"`
+	}
+	if err := json.Unmarshal([]byte(body), &req); err != nil || req.Query == "" {
+		return body
+	}
+	b, err := json.Marshal(req.Variables["input"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req.Query + " " + string(b)
+}
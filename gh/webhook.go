@@ -0,0 +1,165 @@
+package gh
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/k1LoW/gh-triage/profile"
+	"github.com/k1LoW/gh-triage/version"
+)
+
+// webhookSignatureHeader is the header of the HMAC-SHA256 signature of the payload, compatible with X-Hub-Signature-256 of GitHub.
+const webhookSignatureHeader = "X-Gh-Triage-Signature-256"
+
+// webhookTimeout is the timeout of each request of the webhook action.
+const webhookTimeout = 30 * time.Second
+
+// webhookBackoff is the initial interval between retries of the webhook action. It doubles on every retry.
+var webhookBackoff = time.Second
+
+// queueWebhook builds the payload of the webhook action from the fields, and posts it in the background
+// so that a slow endpoint does not block processing the other notifications.
+// The error of posting is reported as the error of the notification when the triage finishes.
+func (c *Client) queueWebhook(ctx context.Context, n *github.Notification, a *profile.RuleAction, m map[string]any, applied func()) error {
+	body, err := c.webhookPayload(a, m)
+	if err != nil {
+		return err
+	}
+	r := ref(m)
+	c.webhookWG.Add(1)
+	go func() {
+		defer c.webhookWG.Done()
+		c.webhookSem <- struct{}{}
+		defer func() { <-c.webhookSem }()
+		// The webhook should be posted even if processing of the page is finished, so only the timeout of each request cancels it.
+		if err := c.postWebhook(context.WithoutCancel(ctx), a, body, r); err != nil {
+			c.addItemError(n, err)
+			return
+		}
+		applied()
+	}()
+	return nil
+}
+
+// waitWebhooks waits for all webhooks being posted.
+func (c *Client) waitWebhooks() {
+	c.webhookWG.Wait()
+}
+
+// postWebhook POSTs the payload to the URL of the webhook action, retrying on failures worth retrying.
+func (c *Client) postWebhook(ctx context.Context, a *profile.RuleAction, body []byte, r string) error {
+	u := os.ExpandEnv(a.URL)
+	retries := a.WebhookRetries()
+	backoff := webhookBackoff
+	for i := 0; ; i++ {
+		retryable, err := c.sendWebhook(ctx, u, a, body)
+		if err == nil {
+			if c.verbose {
+				slog.Info("Webhook sent", "subject", r)
+			}
+			return nil
+		}
+		if !retryable || i >= retries {
+			return fmt.Errorf("failed to send webhook for %s: %w", r, err)
+		}
+		if c.verbose {
+			slog.Warn("Failed to send webhook, retrying", "subject", r, "error", err, "backoff", backoff)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// sendWebhook sends the payload once, and reports whether the failure is worth retrying.
+func (c *Client) sendWebhook(ctx context.Context, u string, a *profile.RuleAction, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gh-triage/"+version.Version)
+	for k, v := range a.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+	if a.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(os.ExpandEnv(a.Secret)))
+		mac.Write(body)
+		req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	res, err := c.webhookClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return false, nil
+	}
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500, fmt.Errorf("unexpected status: %s", res.Status)
+}
+
+// webhookPayload builds the payload of the webhook action.
+// The template renders the whole payload for the json payload, and the message text for the other payloads.
+func (c *Client) webhookPayload(a *profile.RuleAction, m map[string]any) ([]byte, error) {
+	payload := a.Payload
+	if payload == "" {
+		payload = profile.PayloadJSON
+	}
+	var text string
+	if tmpl, ok := c.actionTmpls[a]; ok {
+		buf := new(bytes.Buffer)
+		if err := tmpl.Execute(buf, m); err != nil {
+			return nil, fmt.Errorf("failed to render webhook template: %w", err)
+		}
+		if payload == profile.PayloadJSON {
+			return buf.Bytes(), nil
+		}
+		text = buf.String()
+	}
+	title, _ := m["title"].(string)
+	htmlURL, _ := m["html_url"].(string)
+	summary := strings.TrimSpace(ref(m) + " " + title)
+	switch payload {
+	case profile.PayloadSlack:
+		if text == "" {
+			text = fmt.Sprintf("<%s|%s>", htmlURL, summary)
+		}
+		return marshalJSON(map[string]string{"text": text})
+	case profile.PayloadDiscord:
+		if text == "" {
+			text = fmt.Sprintf("[%s](%s)", summary, htmlURL)
+		}
+		return marshalJSON(map[string]string{"content": text})
+	case profile.PayloadTeams:
+		if text == "" {
+			text = fmt.Sprintf("[%s](%s)", summary, htmlURL)
+		}
+		return marshalJSON(map[string]string{"text": text})
+	default:
+		return marshalJSON(m)
+	}
+}
+
+// marshalJSON marshals v without escaping HTML characters such as < and > used in the link format of Slack.
+func marshalJSON(v any) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := newJSONEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,189 @@
+package gh
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"text/template"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/k1LoW/gh-triage/profile"
+)
+
+func TestPostWebhook(t *testing.T) {
+	webhookBackoff = 0
+	t.Setenv("WEBHOOK_SECRET", "s3cr3t")
+	t.Setenv("WEBHOOK_TOKEN", "t0ken")
+	m := map[string]any{
+		"owner":    "k1LoW",
+		"repo":     "gh-triage",
+		"number":   1,
+		"title":    "Fix bug",
+		"html_url": "https://github.com/k1LoW/gh-triage/pull/1",
+	}
+	tests := []struct {
+		name     string
+		action   *profile.RuleAction
+		statuses []int
+		want     string
+		wantReqs int32
+		wantErr  bool
+	}{
+		{
+			"json",
+			&profile.RuleAction{Type: profile.ActionWebhook},
+			[]int{http.StatusOK},
+			`{"html_url":"https://github.com/k1LoW/gh-triage/pull/1","number":1,"owner":"k1LoW","repo":"gh-triage","title":"Fix bug"}`,
+			1,
+			false,
+		},
+		{
+			"slack",
+			&profile.RuleAction{Type: profile.ActionWebhook, Payload: profile.PayloadSlack},
+			[]int{http.StatusOK},
+			`{"text":"<https://github.com/k1LoW/gh-triage/pull/1|k1LoW/gh-triage #1 Fix bug>"}`,
+			1,
+			false,
+		},
+		{
+			"discord with template",
+			&profile.RuleAction{Type: profile.ActionWebhook, Payload: profile.PayloadDiscord, Template: "New: {{.title}}"},
+			[]int{http.StatusOK},
+			`{"content":"New: Fix bug"}`,
+			1,
+			false,
+		},
+		{
+			"json with template",
+			&profile.RuleAction{Type: profile.ActionWebhook, Template: `{"title":{{json .title}}}`},
+			[]int{http.StatusOK},
+			`{"title":"Fix bug"}`,
+			1,
+			false,
+		},
+		{
+			"retry on server error",
+			&profile.RuleAction{Type: profile.ActionWebhook, Payload: profile.PayloadTeams},
+			[]int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusNoContent},
+			`{"text":"[k1LoW/gh-triage #1 Fix bug](https://github.com/k1LoW/gh-triage/pull/1)"}`,
+			3,
+			false,
+		},
+		{
+			"retries exhausted",
+			&profile.RuleAction{Type: profile.ActionWebhook, Retries: new(int)},
+			[]int{http.StatusBadGateway, http.StatusOK},
+			"",
+			1,
+			true,
+		},
+		{
+			"no retry on client error",
+			&profile.RuleAction{Type: profile.ActionWebhook},
+			[]int{http.StatusBadRequest, http.StatusOK},
+			"",
+			1,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reqs atomic.Int32
+			var got string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := reqs.Add(1) - 1
+				b, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Error(err)
+				}
+				got = string(b)
+				if h := r.Header.Get("Authorization"); h != "Bearer t0ken" {
+					t.Errorf("Authorization = %q", h)
+				}
+				mac := hmac.New(sha256.New, []byte("s3cr3t"))
+				mac.Write(b)
+				if sig := r.Header.Get(webhookSignatureHeader); sig != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+					t.Errorf("invalid signature: %q", sig)
+				}
+				w.WriteHeader(tt.statuses[i])
+			}))
+			defer ts.Close()
+			tt.action.URL = ts.URL
+			tt.action.Secret = "${WEBHOOK_SECRET}"
+			tt.action.Headers = map[string]string{"Authorization": "Bearer ${WEBHOOK_TOKEN}"}
+			c := &Client{
+				webhookClient: ts.Client(),
+				actionTmpls:   map[*profile.RuleAction]*template.Template{},
+			}
+			if err := c.addActionTemplate(tt.action); err != nil {
+				t.Fatal(err)
+			}
+			body, err := c.webhookPayload(tt.action, m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = c.postWebhook(context.Background(), tt.action, body, ref(m))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if n := reqs.Load(); n != tt.wantReqs {
+				t.Errorf("got %d requests, want %d", n, tt.wantReqs)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueueWebhook(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		wantApplied bool
+	}{
+		{"sent", http.StatusOK, true},
+		{"failed", http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+			a := &profile.RuleAction{Type: profile.ActionWebhook, URL: ts.URL}
+			c := &Client{
+				webhookClient: ts.Client(),
+				webhookSem:    make(chan struct{}, 1),
+			}
+			n := &github.Notification{
+				ID:         github.Ptr("1"),
+				Repository: &github.Repository{FullName: github.Ptr("k1LoW/gh-triage")},
+				Subject:    &github.NotificationSubject{Title: github.Ptr("Fix bug")},
+			}
+			var applied atomic.Bool
+			if err := c.queueWebhook(context.Background(), n, a, map[string]any{"number": 1}, func() { applied.Store(true) }); err != nil {
+				t.Fatal(err)
+			}
+			c.waitWebhooks()
+			if got := applied.Load(); got != tt.wantApplied {
+				t.Errorf("got applied %v, want %v", got, tt.wantApplied)
+			}
+			if tt.wantApplied {
+				if len(c.itemErrs) != 0 {
+					t.Errorf("unexpected errors: %v", c.itemErrs)
+				}
+				return
+			}
+			if len(c.itemErrs) != 1 || c.itemErrs[0].ThreadID != "1" {
+				t.Errorf("Expected the error of the notification, got %v", c.itemErrs)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a mutation performed on a notification thread or its subject.
+type Entry struct {
+	RunID         string    `json:"run_id"`                   // ID of the run that performed the mutation
+	Time          time.Time `json:"time"`                     // Time when the mutation was performed
+	ThreadID      string    `json:"thread_id"`                // ID of the notification thread
+	Owner         string    `json:"owner"`                    // Owner of the repository
+	Repo          string    `json:"repo"`                     // Name of the repository
+	Number        int       `json:"number,omitempty"`         // Number of the issue, pull request or discussion
+	SubjectType   string    `json:"subject_type"`             // Type of the subject
+	Title         string    `json:"title"`                    // Title of the subject
+	HTMLURL       string    `json:"html_url,omitempty"`       // URL of the subject
+	Rule          string    `json:"rule"`                     // Name of the rule
+	Condition     string    `json:"condition"`                // Condition that matched
+	Action        string    `json:"action"`                   // Type of the action
+	Labels        []string  `json:"labels,omitempty"`         // Labels actually added or removed (label and unlabel only)
+	Assignees     []string  `json:"assignees,omitempty"`      // Users actually assigned (assign only)
+	Reviewers     []string  `json:"reviewers,omitempty"`      // Users actually requested reviews from (request_review only)
+	TeamReviewers []string  `json:"team_reviewers,omitempty"` // Teams requested reviews from (request_review only)
+	Undo          string    `json:"undo,omitempty"`           // ID of the run undone by this entry
+	Manual        bool      `json:"manual,omitempty"`         // Whether the mutation has to be reverted by hand (undo only)
+}
+
+// Log is an append-only log of mutations.
+type Log struct {
+	path string
+	mu   sync.Mutex
+}
+
+// Path returns the path of the history file of the profile file.
+func Path(profilePath string) string {
+	return strings.TrimSuffix(profilePath, filepath.Ext(profilePath)) + ".history.jsonl"
+}
+
+// New returns the log written to the file.
+func New(p string) *Log {
+	return &Log{path: p}
+}
+
+// NewRunID returns a new ID of a run.
+func NewRunID() string {
+	return time.Now().Format("20060102-150405.000")
+}
+
+// Append appends the entry to the log.
+func (l *Log) Append(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Entries returns all entries in the log in the order they were appended.
+func (l *Log) Entries() ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.Open(l.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var entries []Entry
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for s.Scan() {
+		line++
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid history entry: %w", l.path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// LastRun returns the ID of the last run that has mutations and has not been undone.
+func LastRun(entries []Entry) string {
+	undone := map[string]bool{}
+	for _, e := range entries {
+		if e.Undo != "" {
+			undone[e.Undo] = true
+		}
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Undo == "" && !undone[e.RunID] {
+			return e.RunID
+		}
+	}
+	return ""
+}
+
+// Run returns the entries of the run, excluding entries of undo.
+func Run(entries []Entry, runID string) []Entry {
+	var run []Entry
+	for _, e := range entries {
+		if e.RunID == runID && e.Undo == "" {
+			run = append(run, e)
+		}
+	}
+	return run
+}
@@ -0,0 +1,80 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPath(t *testing.T) {
+	got := Path(filepath.Join("/home/user/.local/share/gh-triage", "work.yml"))
+	want := filepath.Join("/home/user/.local/share/gh-triage", "work.history.jsonl")
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestLog(t *testing.T) {
+	l := New(filepath.Join(t.TempDir(), "gh-triage", "default.history.jsonl"))
+	entries, err := l.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("got %d entries, want 0", len(entries))
+	}
+	for _, e := range []Entry{
+		{RunID: "1", ThreadID: "10", Action: "done"},
+		{RunID: "2", ThreadID: "20", Action: "unsubscribe"},
+		{RunID: "2", ThreadID: "21", Action: "label", Labels: []string{"bug"}},
+	} {
+		if err := l.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err = l.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	if entries[2].Labels[0] != "bug" {
+		t.Errorf("got %v", entries[2])
+	}
+}
+
+func TestLastRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    string
+	}{
+		{"empty", nil, ""},
+		{"last run", []Entry{{RunID: "1"}, {RunID: "2"}}, "2"},
+		{"skip undone run", []Entry{{RunID: "1"}, {RunID: "2"}, {RunID: "3", Undo: "2"}}, "1"},
+		{"all undone", []Entry{{RunID: "1"}, {RunID: "2", Undo: "1"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LastRun(tt.entries); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	entries := []Entry{
+		{RunID: "1", ThreadID: "10"},
+		{RunID: "2", ThreadID: "20"},
+		{RunID: "2", ThreadID: "21"},
+		{RunID: "3", ThreadID: "20", Undo: "2"},
+	}
+	got := Run(entries, "2")
+	if len(got) != 2 || got[0].ThreadID != "20" || got[1].ThreadID != "21" {
+		t.Errorf("got %v", got)
+	}
+	if got := Run(entries, "3"); len(got) != 0 {
+		t.Errorf("Expected no mutations in undo run, got %v", got)
+	}
+}
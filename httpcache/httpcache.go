@@ -0,0 +1,218 @@
+// Package httpcache provides an on-disk cache of HTTP responses that are revalidated with ETag.
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// retention is how long entries that are no longer used are kept in the cache.
+	retention = 7 * 24 * time.Hour
+	// FromCacheHeader is the header set on responses served from the cache.
+	FromCacheHeader = "X-From-Cache"
+)
+
+// Cache is an on-disk cache of HTTP responses keyed by URL.
+// Cached responses are always revalidated with If-None-Match, and served only if the server responds 304 Not Modified,
+// so a stale or someone else's response is never returned.
+type Cache struct {
+	dir string
+}
+
+// entry is a cached response.
+type entry struct {
+	URL        string      `json:"url"`
+	ETag       string      `json:"etag"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// Dir returns the directory of the cache.
+func Dir() string {
+	if os.Getenv("XDG_CACHE_HOME") != "" {
+		return filepath.Join(os.Getenv("XDG_CACHE_HOME"), "gh-triage")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".cache", "gh-triage")
+}
+
+// Open opens the cache in the directory, dropping entries that have not been used for a while.
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		info, err := f.Info()
+		if err != nil || f.IsDir() {
+			continue
+		}
+		if time.Since(info.ModTime()) > retention {
+			_ = os.Remove(filepath.Join(dir, f.Name()))
+		}
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Transport returns the transport that caches responses of the base transport.
+func (c *Cache) Transport(base http.RoundTripper) http.RoundTripper {
+	return &transport{cache: c, base: base}
+}
+
+func (c *Cache) load(key string) (*entry, error) {
+	b, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, err
+	}
+	e := &entry{}
+	if err := json.Unmarshal(b, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (c *Cache) store(key string, e *entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it so that entries are never left half-written.
+	f, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), c.path(key))
+}
+
+// touch marks the entry as used so that it is kept in the cache.
+func (c *Cache) touch(key string) {
+	now := time.Now()
+	_ = os.Chtimes(c.path(key), now, now)
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// transport revalidates cached responses with If-None-Match, and caches responses that have ETag.
+type transport struct {
+	cache *Cache
+	base  http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !cacheable(req) {
+		return t.base.RoundTrip(req)
+	}
+	key := cacheKey(req)
+	e, err := t.cache.load(key)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("Failed to read the cache, ignoring it", "url", req.URL.String(), "error", err)
+	}
+	r := req
+	if e != nil {
+		r = req.Clone(req.Context())
+		r.Header.Set("If-None-Match", e.ETag)
+	}
+	res, err := t.base.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	if e != nil && res.StatusCode == http.StatusNotModified {
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+		t.cache.touch(key)
+		return e.response(req, res.Header), nil
+	}
+	etag := res.Header.Get("ETag")
+	if res.StatusCode != http.StatusOK || etag == "" {
+		return res, nil
+	}
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	if err := t.cache.store(key, &entry{
+		URL:        req.URL.String(),
+		ETag:       etag,
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       body,
+	}); err != nil {
+		slog.Warn("Failed to write the cache", "url", req.URL.String(), "error", err)
+	}
+	return res, nil
+}
+
+// response returns the cached response, with the headers of the 304 response such as X-RateLimit-Remaining taking precedence.
+func (e *entry) response(req *http.Request, header http.Header) *http.Response {
+	h := e.Header.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	for k, v := range header {
+		if k == "Content-Length" {
+			continue
+		}
+		h[k] = v
+	}
+	h.Set(FromCacheHeader, "1")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// cacheable reports whether the response of the request can be cached.
+// Requests that are already conditional are left to the caller, such as notifications with If-Modified-Since in watch mode.
+func cacheable(req *http.Request) bool {
+	if req.Method != http.MethodGet {
+		return false
+	}
+	for _, h := range []string{"If-None-Match", "If-Modified-Since", "Range"} {
+		if req.Header.Get(h) != "" {
+			return false
+		}
+	}
+	// The rate limit is not counted, and has to be up to date.
+	return !strings.HasSuffix(req.URL.Path, "/rate_limit")
+}
+
+// cacheKey returns the key of the request. Responses for different media types are cached separately.
+func cacheKey(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String() + "\n" + req.Header.Get("Accept")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,145 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	body := `{"number":1}`
+	etag := `"v1"`
+	var requests, notModified int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-RateLimit-Remaining", "100")
+		if r.URL.Path == "/rate_limit" {
+			w.Header().Set("ETag", `"rate"`)
+			_, _ = io.WriteString(w, "{}")
+			return
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.Header().Set("X-RateLimit-Remaining", "99")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = io.WriteString(w, body)
+	}))
+	defer ts.Close()
+	dir := t.TempDir()
+	c, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: c.Transport(http.DefaultTransport)}
+	get := func(t *testing.T, path string) (*http.Response, string) {
+		t.Helper()
+		res, err := client.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res, string(b)
+	}
+
+	t.Run("miss", func(t *testing.T) {
+		res, got := get(t, "/repos/owner/repo/pulls/1")
+		if res.StatusCode != http.StatusOK || got != body || res.Header.Get(FromCacheHeader) != "" {
+			t.Errorf("got %d %q %v", res.StatusCode, got, res.Header)
+		}
+	})
+
+	t.Run("revalidated", func(t *testing.T) {
+		res, got := get(t, "/repos/owner/repo/pulls/1")
+		if res.StatusCode != http.StatusOK || got != body || res.Header.Get(FromCacheHeader) != "1" {
+			t.Errorf("got %d %q %v", res.StatusCode, got, res.Header)
+		}
+		if got := res.Header.Get("X-RateLimit-Remaining"); got != "99" {
+			t.Errorf("Expected the headers of the 304 response, got X-RateLimit-Remaining %q", got)
+		}
+		if notModified != 1 {
+			t.Errorf("got %d 304 responses, want 1", notModified)
+		}
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		body = `{"number":1,"state":"closed"}`
+		etag = `"v2"`
+		res, got := get(t, "/repos/owner/repo/pulls/1")
+		if got != body || res.Header.Get(FromCacheHeader) != "" {
+			t.Errorf("got %q %v", got, res.Header)
+		}
+		res, got = get(t, "/repos/owner/repo/pulls/1")
+		if got != body || res.Header.Get(FromCacheHeader) != "1" {
+			t.Errorf("got %q %v", got, res.Header)
+		}
+	})
+
+	t.Run("not cacheable", func(t *testing.T) {
+		before := requests
+		get(t, "/rate_limit")
+		res, _ := get(t, "/rate_limit")
+		if res.Header.Get(FromCacheHeader) != "" || requests-before != 2 {
+			t.Errorf("Expected the rate limit not to be cached, got %v", res.Header)
+		}
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/repos/owner/repo/pulls/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("If-Modified-Since", time.Now().UTC().Format(http.TimeFormat))
+		res, err = client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+		if res.Header.Get(FromCacheHeader) != "" {
+			t.Errorf("Expected the conditional request to be left to the caller, got %v", res.Header)
+		}
+	})
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.json")
+	recent := filepath.Join(dir, "recent.json")
+	for _, p := range []string{old, recent} {
+		if err := os.WriteFile(p, []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	past := time.Now().Add(-retention - time.Hour)
+	if err := os.Chtimes(old, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed, got %v", old, err)
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Errorf("Expected %s to be kept, got %v", recent, err)
+	}
+}
+
+func TestDir(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", "/tmp/cache")
+	if got := Dir(); got != filepath.Join("/tmp/cache", "gh-triage") {
+		t.Errorf("got %s", got)
+	}
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("HOME", "/home/alice")
+	if got := Dir(); got != filepath.Join("/home/alice", ".cache", "gh-triage") {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,44 @@
+package profile
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/k1LoW/duration"
+)
+
+// SinceTime returns the time to fetch notifications updated after, or the zero time if not set.
+func (p *Profile) SinceTime(now time.Time) (time.Time, error) {
+	t, err := parseTime(p.Since, now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid since: %w", err)
+	}
+	return t, nil
+}
+
+// BeforeTime returns the time to fetch notifications updated before, or the zero time if not set.
+func (p *Profile) BeforeTime(now time.Time) (time.Time, error) {
+	t, err := parseTime(p.Before, now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid before: %w", err)
+	}
+	return t, nil
+}
+
+// parseTime parses a timestamp such as 2025-01-01T00:00:00Z or 2025-01-01, or a duration such as 7d as the time before now.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	d, err := duration.Parse(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a timestamp nor a duration", s)
+	}
+	return now.Add(-d), nil
+}
@@ -0,0 +1,66 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2025, 8, 4, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"", time.Time{}, false},
+		{"2025-08-01T09:00:00Z", time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC), false},
+		{"2025-08-01", time.Date(2025, 8, 1, 0, 0, 0, 0, time.Local), false},
+		{"7d", now.Add(-7 * 24 * time.Hour), false},
+		{"12hours", now.Add(-12 * time.Hour), false},
+		{"yesterday", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseTime(tt.in, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFile_InvalidFetchFilter(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "default.yml")
+	content := "all: true\nsince: yesterday\nrules:\n  - max: 1\n    conditions:\n      - \"*\"\n    actions:\n      - list\n"
+	if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadFile(p)
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), ":2: invalid since") {
+		t.Errorf("Expected error with line of since, got %v", err)
+	}
+}
+
+func TestLoadFile_InvalidRepository(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "default.yml")
+	content := "repositories:\n  - k1LoW/gh-triage\n  - k1LoW\nrules:\n  - max: 1\n    conditions:\n      - \"*\"\n    actions:\n      - list\n"
+	if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadFile(p)
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), ":3: invalid repository: \"k1LoW\"") {
+		t.Errorf("Expected error with line of repository, got %v", err)
+	}
+}
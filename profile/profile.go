@@ -1,19 +1,59 @@
 package profile
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/k1LoW/gh-triage/cond"
 )
 
 type Action struct {
-	Max        int      `yaml:"max"`        // Maximum number of issues/pull requests to process
-	Conditions []string `yaml:"conditions"` // Conditions to match issues/pull requests
+	Max        int      `yaml:"max"`                // Maximum number of issues/pull requests to process
+	Conditions []string `yaml:"conditions"`         // Conditions to match issues/pull requests
+	Template   string   `yaml:"template,omitempty"` // Go text/template to render each item (list only)
+}
+
+// ConditionError is an error of a condition that can not be compiled.
+type ConditionError struct {
+	File      string // Path of the profile
+	Line      int    // Line of the condition in the profile
+	Path      string // Path of the condition such as done.conditions[0]
+	Condition string
+	Err       error
+}
+
+func (e *ConditionError) Error() string {
+	return fmt.Sprintf("%s:%d: invalid condition in %s: %q: %v", e.File, e.Line, e.Path, e.Condition, e.Err)
+}
+
+func (e *ConditionError) Unwrap() error {
+	return e.Err
 }
 
 type Profile struct {
+	// Scopes of notifications. Notifications from other repositories are never processed.
+	Repositories []string `yaml:"repositories,omitempty"` // Repositories in the form of owner/repo
+	Owners       []string `yaml:"owners,omitempty"`       // Owners (users or organizations) of repositories
+
+	// Filters of notifications to fetch.
+	All           bool   `yaml:"all,omitempty"`           // Fetch read notifications too
+	Participating bool   `yaml:"participating,omitempty"` // Fetch only notifications in which the user is directly participating or mentioned
+	Since         string `yaml:"since,omitempty"`         // Fetch only notifications updated after the time, such as 2025-01-01 or 7d (7 days ago)
+	Before        string `yaml:"before,omitempty"`        // Fetch only notifications updated before the time, such as 2025-01-01 or 7d (7 days ago)
+
+	Concurrency int `yaml:"concurrency,omitempty"` // Number of notifications processed at the same time. Defaults to 8
+
+	Rules []Rule `yaml:"rules,omitempty"` // Rules evaluated in order
+
+	// Legacy format. These are converted to rules by RuleSet, and can not be used with Rules.
 	Done        Action `yaml:"done,omitempty"`        // Mark as done issues/pull requests that match the conditions
 	Unsubscribe Action `yaml:"unsubscribe,omitempty"` // Unsubscribe from issues/pull requests that match the conditions
 	Read        Action `yaml:"read,omitempty"`        // Mark as read issues/pull requests that match the conditions
@@ -22,31 +62,37 @@ type Profile struct {
 }
 
 var defaultProfile = &Profile{
-	Done: Action{
-		Max: 1000,
-		Conditions: []string{
-			"merged",
-			"closed",
+	Rules: []Rule{
+		{
+			Name:       "List all unread notifications",
+			Max:        1000,
+			Conditions: []string{"*"},
+			Actions:    []RuleAction{{Type: ActionList}},
+			Continue:   true,
+		},
+		{
+			Name:       "Open PRs awaiting my review",
+			Max:        1,
+			Conditions: []string{"is_pull_request && me in reviewers && passed && !approved && open && !draft"},
+			Actions:    []RuleAction{{Type: ActionOpen}},
+		},
+		{
+			Name: "Mark merged and closed PRs / issues as done",
+			Max:  1000,
+			Conditions: []string{
+				"merged",
+				"closed",
+			},
+			Actions: []RuleAction{{Type: ActionDone}},
 		},
-	},
-	Unsubscribe: Action{
-		Max:        0,
-		Conditions: []string{},
-	},
-	Read: Action{
-		Max:        0,
-		Conditions: []string{},
-	},
-	Open: Action{
-		Max:        1,
-		Conditions: []string{"is_pull_request && me in reviewers && passed && !approved && open && !draft"},
-	},
-	List: Action{
-		Max:        1000,
-		Conditions: []string{"*"},
 	},
 }
 
+// Path returns the path of the configuration file of the profile.
+func Path(name string) string {
+	return profilePathWithName(name)
+}
+
 func profilePathWithName(name string) string {
 	var dataHomePath string
 	if os.Getenv("XDG_DATA_HOME") != "" {
@@ -107,6 +153,11 @@ func Load(name string) (*Profile, error) {
 		slog.Info("created config file", "path", p)
 		return defaultProfile, nil
 	}
+	return LoadFile(p)
+}
+
+// LoadFile loads the profile from the file and validates its conditions.
+func LoadFile(p string) (*Profile, error) {
 	b, err := os.ReadFile(p)
 	if err != nil {
 		return nil, err
@@ -115,5 +166,99 @@ func Load(name string) (*Profile, error) {
 	if err := yaml.Unmarshal(b, &p2); err != nil {
 		return nil, err
 	}
+	if err := p2.validate(p, b); err != nil {
+		return nil, err
+	}
 	return &p2, nil
 }
+
+// validate compiles all conditions and checks all actions, and reports the file and line of the broken ones.
+func (p *Profile) validate(file string, b []byte) error {
+	if len(p.Rules) > 0 && p.isLegacy() {
+		return fmt.Errorf("%s: rules can not be used with done, unsubscribe, read, open and list", file)
+	}
+	var errs []error
+	if p.Concurrency < 0 {
+		errs = append(errs, fmt.Errorf("%s:%d: invalid concurrency: %d", file, lineOf(b, "$.concurrency"), p.Concurrency))
+	}
+	for i, r := range p.Repositories {
+		if owner, repo, ok := strings.Cut(r, "/"); !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+			errs = append(errs, fmt.Errorf("%s:%d: invalid repository: %q (must be owner/repo)", file, lineOf(b, fmt.Sprintf("$.repositories[%d]", i)), r))
+		}
+	}
+	if _, err := p.SinceTime(time.Now()); err != nil {
+		errs = append(errs, fmt.Errorf("%s:%d: %w", file, lineOf(b, "$.since"), err))
+	}
+	if _, err := p.BeforeTime(time.Now()); err != nil {
+		errs = append(errs, fmt.Errorf("%s:%d: %w", file, lineOf(b, "$.before"), err))
+	}
+	conditionErr := func(pathStr, c string, err error) error {
+		return &ConditionError{
+			File:      file,
+			Line:      lineOf(b, pathStr),
+			Path:      strings.TrimPrefix(pathStr, "$."),
+			Condition: c,
+			Err:       err,
+		}
+	}
+	if len(p.Rules) == 0 {
+		actions := []struct {
+			name   string
+			action Action
+		}{
+			{ActionDone, p.Done},
+			{ActionUnsubscribe, p.Unsubscribe},
+			{ActionRead, p.Read},
+			{ActionOpen, p.Open},
+			{ActionList, p.List},
+		}
+		for _, a := range actions {
+			for i, c := range a.action.Conditions {
+				if _, err := cond.Compile(c); err != nil {
+					errs = append(errs, conditionErr(fmt.Sprintf("$.%s.conditions[%d]", a.name, i), c, err))
+				}
+			}
+		}
+		return errors.Join(errs...)
+	}
+	for i, r := range p.Rules {
+		for j, c := range r.Conditions {
+			if _, err := cond.Compile(c); err != nil {
+				errs = append(errs, conditionErr(fmt.Sprintf("$.rules[%d].conditions[%d]", i, j), c, err))
+			}
+		}
+		if len(r.Actions) == 0 {
+			pathStr := fmt.Sprintf("$.rules[%d]", i)
+			errs = append(errs, fmt.Errorf("%s:%d: no actions in %s", file, lineOf(b, pathStr), strings.TrimPrefix(pathStr, "$.")))
+		}
+		if r.Name == "" && slices.ContainsFunc(r.Actions, func(a RuleAction) bool { return a.Once }) {
+			// The name identifies the actions of the rule in the state file.
+			pathStr := fmt.Sprintf("$.rules[%d]", i)
+			errs = append(errs, fmt.Errorf("%s:%d: name is required in %s with once", file, lineOf(b, pathStr), strings.TrimPrefix(pathStr, "$.")))
+		}
+		for j, a := range r.Actions {
+			if err := a.validate(); err != nil {
+				pathStr := fmt.Sprintf("$.rules[%d].actions[%d]", i, j)
+				errs = append(errs, fmt.Errorf("%s:%d: invalid action in %s: %w", file, lineOf(b, pathStr), strings.TrimPrefix(pathStr, "$."), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// lineOf returns the line of the node specified by the YAML path, or 0 if it is not found.
+func lineOf(b []byte, pathStr string) int {
+	yp, err := yaml.PathString(pathStr)
+	if err != nil {
+		return 0
+	}
+	f, err := parser.ParseBytes(b, 0)
+	if err != nil {
+		return 0
+	}
+	n, err := yp.FilterFile(f)
+	if err != nil || n == nil {
+		return 0
+	}
+	return n.GetToken().Position.Line
+}
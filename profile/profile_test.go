@@ -2,6 +2,7 @@ package profile
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -86,15 +87,15 @@ func TestLoad_Migration(t *testing.T) {
 	customProfile := &Profile{
 		Read: Action{
 			Max:        500,
-			Conditions: []string{"custom_condition"},
+			Conditions: []string{"'custom' in labels"},
 		},
 		Open: Action{
 			Max:        2,
-			Conditions: []string{"custom_open_condition"},
+			Conditions: []string{"is_pull_request"},
 		},
 		List: Action{
 			Max:        100,
-			Conditions: []string{"custom_list_condition"},
+			Conditions: []string{"is_issue"},
 		},
 	}
 
@@ -129,8 +130,8 @@ func TestLoad_Migration(t *testing.T) {
 	if profile.Read.Max != 500 {
 		t.Errorf("Expected Read.Max=500, got %d", profile.Read.Max)
 	}
-	if len(profile.Read.Conditions) != 1 || profile.Read.Conditions[0] != "custom_condition" {
-		t.Errorf("Expected Read.Conditions=[custom_condition], got %v", profile.Read.Conditions)
+	if len(profile.Read.Conditions) != 1 || profile.Read.Conditions[0] != "'custom' in labels" {
+		t.Errorf("Expected Read.Conditions=['custom' in labels], got %v", profile.Read.Conditions)
 	}
 
 	// Check that default.yml was created
@@ -218,15 +219,15 @@ func TestLoad_ExistingFile(t *testing.T) {
 	existingProfile := &Profile{
 		Read: Action{
 			Max:        750,
-			Conditions: []string{"existing_condition_1", "existing_condition_2"},
+			Conditions: []string{"'existing_1' in labels", "'existing_2' in labels"},
 		},
 		Open: Action{
 			Max:        3,
-			Conditions: []string{"existing_open_condition"},
+			Conditions: []string{"is_discussion"},
 		},
 		List: Action{
 			Max:        200,
-			Conditions: []string{"existing_list_condition"},
+			Conditions: []string{"is_release"},
 		},
 	}
 
@@ -253,8 +254,8 @@ func TestLoad_ExistingFile(t *testing.T) {
 	if len(profile.Read.Conditions) != 2 {
 		t.Errorf("Expected Read.Conditions length=2, got %d", len(profile.Read.Conditions))
 	}
-	if profile.Read.Conditions[0] != "existing_condition_1" || profile.Read.Conditions[1] != "existing_condition_2" {
-		t.Errorf("Expected Read.Conditions=[existing_condition_1, existing_condition_2], got %v", profile.Read.Conditions)
+	if profile.Read.Conditions[0] != "'existing_1' in labels" || profile.Read.Conditions[1] != "'existing_2' in labels" {
+		t.Errorf("Expected Read.Conditions=['existing_1' in labels, 'existing_2' in labels], got %v", profile.Read.Conditions)
 	}
 
 	// Test loading existing profile config
@@ -303,3 +304,61 @@ func TestLoad_InvalidYAML(t *testing.T) {
 		t.Error("Expected error when loading invalid YAML, got nil")
 	}
 }
+
+func TestLoadFile_InvalidCondition(t *testing.T) {
+	tempDir := t.TempDir()
+	p := filepath.Join(tempDir, "shared.yml")
+	content := `done:
+  max: 100
+  conditions:
+    - "merged"
+    - "mergd"
+list:
+  max: 10
+  conditions:
+    - "number"
+`
+	if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	_, err := LoadFile(p)
+	if err == nil {
+		t.Fatal("Expected error when loading invalid condition, got nil")
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("Expected joined errors, got %T", err)
+	}
+	var errs []*ConditionError
+	for _, e := range joined.Unwrap() {
+		var ce *ConditionError
+		if !errors.As(e, &ce) {
+			t.Fatalf("Expected ConditionError, got %T", e)
+		}
+		errs = append(errs, ce)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %d: %v", len(errs), err)
+	}
+	if errs[0].Path != "done.conditions[1]" || errs[0].Line != 5 || errs[0].File != p {
+		t.Errorf("Unexpected error: %+v", errs[0])
+	}
+	if errs[1].Path != "list.conditions[0]" || errs[1].Line != 9 {
+		t.Errorf("Unexpected error: %+v", errs[1])
+	}
+}
+
+func TestLoadFile_Default(t *testing.T) {
+	b, err := yaml.Marshal(defaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(t.TempDir(), "default.yml")
+	if err := os.WriteFile(p, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(p); err != nil {
+		t.Errorf("Default profile should be valid: %v", err)
+	}
+}
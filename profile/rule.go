@@ -0,0 +1,200 @@
+package profile
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/k1LoW/duration"
+	"github.com/samber/lo"
+)
+
+// Action types.
+const (
+	ActionOpen        = "open"
+	ActionDone        = "done"
+	ActionUnsubscribe = "unsubscribe"
+	ActionRead        = "read"
+	ActionList        = "list"
+
+	ActionLabel         = "label"
+	ActionUnlabel       = "unlabel"
+	ActionComment       = "comment"
+	ActionAssign        = "assign"
+	ActionRequestReview = "request_review"
+
+	ActionExec    = "exec"
+	ActionWebhook = "webhook"
+	ActionNotify  = "notify"
+)
+
+// Payload formats of the webhook action.
+const (
+	PayloadJSON    = "json"
+	PayloadSlack   = "slack"
+	PayloadDiscord = "discord"
+	PayloadTeams   = "teams"
+)
+
+// Payloads is the list of supported payload formats of the webhook action.
+var Payloads = []string{PayloadJSON, PayloadSlack, PayloadDiscord, PayloadTeams}
+
+// DefaultExecTimeout is the default timeout of the exec action.
+const DefaultExecTimeout = time.Minute
+
+// DefaultWebhookRetries is the default number of retries of the webhook action.
+const DefaultWebhookRetries = 3
+
+// ActionTypes is the list of supported action types.
+var ActionTypes = []string{ActionOpen, ActionDone, ActionUnsubscribe, ActionRead, ActionList, ActionLabel, ActionUnlabel, ActionComment, ActionAssign, ActionRequestReview, ActionExec, ActionWebhook, ActionNotify}
+
+// Rule is a set of conditions and the actions performed on notifications that match the conditions.
+// Rules are evaluated in order, and evaluation stops at the first matched rule unless Continue is set.
+type Rule struct {
+	Name       string       `yaml:"name,omitempty"`     // Name of the rule
+	Max        int          `yaml:"max"`                // Maximum number of issues/pull requests to process
+	Conditions []string     `yaml:"conditions"`         // Conditions to match issues/pull requests
+	Actions    []RuleAction `yaml:"actions"`            // Actions performed on issues/pull requests that match the conditions
+	Continue   bool         `yaml:"continue,omitempty"` // Continue evaluating subsequent rules after this rule matched
+	Always     bool         `yaml:"-"`                  // Evaluate the rule even if a previous rule stopped the evaluation (legacy list only)
+}
+
+// RuleAction is an action of a rule. It can be written as a string such as "done" if it has no parameters.
+type RuleAction struct {
+	Type          string            `yaml:"type"`                     // Type of the action
+	Once          bool              `yaml:"once,omitempty"`           // Perform the action only once per thread until the thread is updated
+	Template      string            `yaml:"template,omitempty"`       // Go text/template to render each item (list, webhook and notify only)
+	Labels        []string          `yaml:"labels,omitempty"`         // Labels to add or remove (label and unlabel only)
+	Body          string            `yaml:"body,omitempty"`           // Body of the comment (comment only)
+	Assignees     []string          `yaml:"assignees,omitempty"`      // Users to assign. The authenticated user if empty (assign only)
+	Reviewers     []string          `yaml:"reviewers,omitempty"`      // Users to request reviews from (request_review only)
+	TeamReviewers []string          `yaml:"team_reviewers,omitempty"` // Team slugs to request reviews from (request_review only)
+	Command       string            `yaml:"command,omitempty"`        // Shell command to run (exec only)
+	Timeout       string            `yaml:"timeout,omitempty"`        // Timeout of the command such as 30s (exec only)
+	Concurrency   int               `yaml:"concurrency,omitempty"`    // Maximum number of commands running at the same time. Defaults to 1 (exec only)
+	FailOnError   bool              `yaml:"fail_on_error,omitempty"`  // Fail the triage if the command exits with non-zero status (exec only)
+	URL           string            `yaml:"url,omitempty"`            // URL to POST to. Environment variables are expanded (webhook only)
+	Headers       map[string]string `yaml:"headers,omitempty"`        // Additional HTTP headers. Environment variables are expanded (webhook only)
+	Secret        string            `yaml:"secret,omitempty"`         // Secret to sign the payload with HMAC-SHA256. Environment variables are expanded (webhook only)
+	Payload       string            `yaml:"payload,omitempty"`        // Payload format: json, slack, discord or teams. Defaults to json (webhook only)
+	Retries       *int              `yaml:"retries,omitempty"`        // Number of retries on failure. Defaults to 3 (webhook only)
+}
+
+// DisplayName returns the name of the rule, or the names of its actions if it has no name.
+func (r Rule) DisplayName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return strings.Join(lo.Map(r.Actions, func(a RuleAction, _ int) string {
+		return a.Type
+	}), ", ")
+}
+
+// MarshalYAML marshals the action as a string if it has no parameters.
+func (a RuleAction) MarshalYAML() (any, error) {
+	if reflect.DeepEqual(a, RuleAction{Type: a.Type}) {
+		return a.Type, nil
+	}
+	type alias RuleAction
+	return alias(a), nil
+}
+
+// UnmarshalYAML unmarshals the action from a string or a mapping.
+func (a *RuleAction) UnmarshalYAML(unmarshal func(any) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		*a = RuleAction{Type: s}
+		return nil
+	}
+	type alias RuleAction
+	var v alias
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	*a = RuleAction(v)
+	return nil
+}
+
+// ExecTimeout returns the timeout of the exec action.
+func (a RuleAction) ExecTimeout() (time.Duration, error) {
+	if a.Timeout == "" {
+		return DefaultExecTimeout, nil
+	}
+	d, err := duration.Parse(a.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", a.Timeout, err)
+	}
+	return d, nil
+}
+
+// WebhookRetries returns the number of retries of the webhook action.
+func (a RuleAction) WebhookRetries() int {
+	if a.Retries == nil {
+		return DefaultWebhookRetries
+	}
+	return *a.Retries
+}
+
+func (a RuleAction) validate() error {
+	if !slices.Contains(ActionTypes, a.Type) {
+		return fmt.Errorf("unsupported action type: %q (supported: %s)", a.Type, strings.Join(ActionTypes, ", "))
+	}
+	switch a.Type {
+	case ActionLabel, ActionUnlabel:
+		if len(a.Labels) == 0 {
+			return fmt.Errorf("%s action requires labels", a.Type)
+		}
+	case ActionComment:
+		if a.Body == "" {
+			return fmt.Errorf("%s action requires body", a.Type)
+		}
+	case ActionRequestReview:
+		if len(a.Reviewers) == 0 && len(a.TeamReviewers) == 0 {
+			return fmt.Errorf("%s action requires reviewers or team_reviewers", a.Type)
+		}
+	case ActionExec:
+		if a.Command == "" {
+			return fmt.Errorf("%s action requires command", a.Type)
+		}
+		if _, err := a.ExecTimeout(); err != nil {
+			return err
+		}
+		if a.Concurrency < 0 {
+			return fmt.Errorf("invalid concurrency: %d", a.Concurrency)
+		}
+	case ActionWebhook:
+		if a.URL == "" {
+			return fmt.Errorf("%s action requires url", a.Type)
+		}
+		if a.Payload != "" && !slices.Contains(Payloads, a.Payload) {
+			return fmt.Errorf("unsupported payload: %q (supported: %s)", a.Payload, strings.Join(Payloads, ", "))
+		}
+		if a.WebhookRetries() < 0 {
+			return fmt.Errorf("invalid retries: %d", a.WebhookRetries())
+		}
+	}
+	return nil
+}
+
+// RuleSet returns the rules of the profile.
+// A profile written in the legacy format (done, unsubscribe, read, open and list) is converted to rules
+// that keep its precedence: open > done > unsubscribe > read. The list rule comes last and is evaluated
+// even if one of the others matched, so that it sees unread set to false by them as before.
+func (p *Profile) RuleSet() []Rule {
+	if len(p.Rules) > 0 {
+		return p.Rules
+	}
+	return []Rule{
+		{Name: ActionOpen, Max: p.Open.Max, Conditions: p.Open.Conditions, Actions: []RuleAction{{Type: ActionOpen}}},
+		{Name: ActionDone, Max: p.Done.Max, Conditions: p.Done.Conditions, Actions: []RuleAction{{Type: ActionDone}}},
+		{Name: ActionUnsubscribe, Max: p.Unsubscribe.Max, Conditions: p.Unsubscribe.Conditions, Actions: []RuleAction{{Type: ActionUnsubscribe}}},
+		{Name: ActionRead, Max: p.Read.Max, Conditions: p.Read.Conditions, Actions: []RuleAction{{Type: ActionRead}}},
+		{Name: ActionList, Max: p.List.Max, Conditions: p.List.Conditions, Actions: []RuleAction{{Type: ActionList, Template: p.List.Template}}, Always: true},
+	}
+}
+
+func (p *Profile) isLegacy() bool {
+	return len(p.Done.Conditions) > 0 || len(p.Unsubscribe.Conditions) > 0 || len(p.Read.Conditions) > 0 || len(p.Open.Conditions) > 0 || len(p.List.Conditions) > 0
+}
@@ -0,0 +1,198 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+)
+
+func TestRuleSet_Legacy(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *Profile
+	}{
+		{
+			"list all",
+			&Profile{
+				Done: Action{Max: 10, Conditions: []string{"merged"}},
+				Open: Action{Max: 1, Conditions: []string{"me in reviewers"}},
+				List: Action{Max: 100, Conditions: []string{"*"}, Template: "{{.title}}"},
+			},
+		},
+		{
+			"list unread",
+			&Profile{
+				Done: Action{Max: 10, Conditions: []string{"merged"}},
+				Open: Action{Max: 1, Conditions: []string{"me in reviewers"}},
+				List: Action{Max: 100, Conditions: []string{"unread"}, Template: "{{.title}}"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := tt.p.RuleSet()
+			want := []struct {
+				name     string
+				max      int
+				action   string
+				always   bool
+				template string
+				conds    []string
+			}{
+				{ActionOpen, 1, ActionOpen, false, "", tt.p.Open.Conditions},
+				{ActionDone, 10, ActionDone, false, "", tt.p.Done.Conditions},
+				{ActionUnsubscribe, 0, ActionUnsubscribe, false, "", nil},
+				{ActionRead, 0, ActionRead, false, "", nil},
+				// The list rule comes last, so that its conditions see unread set to false by the other actions.
+				{ActionList, 100, ActionList, true, "{{.title}}", tt.p.List.Conditions},
+			}
+			if len(rules) != len(want) {
+				t.Fatalf("Expected %d rules, got %d", len(want), len(rules))
+			}
+			for i, w := range want {
+				r := rules[i]
+				if r.Name != w.name || r.Max != w.max || r.Continue || r.Always != w.always || len(r.Actions) != 1 || r.Actions[0].Type != w.action || r.Actions[0].Template != w.template || !slices.Equal(r.Conditions, w.conds) {
+					t.Errorf("rules[%d] = %+v, want %+v", i, r, w)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFile_Rules(t *testing.T) {
+	content := `rules:
+  - name: cleanup
+    max: 100
+    conditions:
+      - "closed"
+    actions:
+      - unsubscribe
+      - done
+  - max: 10
+    conditions:
+      - "*"
+    actions:
+      - type: list
+        template: "{{.title}}"
+    continue: true
+`
+	p := filepath.Join(t.TempDir(), "rules.yml")
+	if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	profile, err := LoadFile(p)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	rules := profile.RuleSet()
+	if len(rules) != 2 {
+		t.Fatalf("Expected 2 rules, got %d", len(rules))
+	}
+	if got := rules[0].DisplayName(); got != "cleanup" {
+		t.Errorf("Expected name=cleanup, got %s", got)
+	}
+	if len(rules[0].Actions) != 2 || rules[0].Actions[0].Type != ActionUnsubscribe || rules[0].Actions[1].Type != ActionDone {
+		t.Errorf("Unexpected actions: %+v", rules[0].Actions)
+	}
+	if rules[0].Continue {
+		t.Error("Expected continue=false")
+	}
+	if got := rules[1].DisplayName(); got != "list" {
+		t.Errorf("Expected name=list, got %s", got)
+	}
+	if rules[1].Actions[0].Template != "{{.title}}" || !rules[1].Continue {
+		t.Errorf("Unexpected rule: %+v", rules[1])
+	}
+
+	// Actions without parameters are marshaled as strings
+	b, err := yaml.Marshal(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "- unsubscribe\n") || !strings.Contains(string(b), "type: list") {
+		t.Errorf("Unexpected marshaled profile:\n%s", b)
+	}
+}
+
+func TestLoadFile_InvalidRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			"unsupported action",
+			"rules:\n  - max: 1\n    conditions:\n      - \"*\"\n    actions:\n      - archive\n",
+			":6: invalid action in rules[0].actions[0]",
+		},
+		{
+			"label without labels",
+			"rules:\n  - max: 1\n    conditions:\n      - \"*\"\n    actions:\n      - label\n",
+			"label action requires labels",
+		},
+		{
+			"request_review without reviewers",
+			"rules:\n  - max: 1\n    conditions:\n      - \"*\"\n    actions:\n      - type: request_review\n",
+			"request_review action requires reviewers or team_reviewers",
+		},
+		{
+			"exec without command",
+			"rules:\n  - max: 1\n    conditions:\n      - \"*\"\n    actions:\n      - exec\n",
+			"exec action requires command",
+		},
+		{
+			"exec with invalid timeout",
+			"rules:\n  - max: 1\n    conditions:\n      - \"*\"\n    actions:\n      - type: exec\n        command: echo\n        timeout: soon\n",
+			"invalid timeout \"soon\"",
+		},
+		{
+			"webhook without url",
+			"rules:\n  - max: 1\n    conditions:\n      - \"*\"\n    actions:\n      - webhook\n",
+			"webhook action requires url",
+		},
+		{
+			"webhook with unsupported payload",
+			"rules:\n  - max: 1\n    conditions:\n      - \"*\"\n    actions:\n      - type: webhook\n        url: https://example.com\n        payload: irc\n",
+			"unsupported payload: \"irc\"",
+		},
+		{
+			"once without name",
+			"rules:\n  - max: 1\n    conditions:\n      - \"*\"\n    actions:\n      - type: open\n        once: true\n",
+			":2: name is required in rules[0] with once",
+		},
+		{
+			"no actions",
+			"rules:\n  - max: 1\n    conditions:\n      - \"*\"\n",
+			":2: no actions in rules[0]",
+		},
+		{
+			"invalid condition",
+			"rules:\n  - max: 1\n    conditions:\n      - \"mergd\"\n    actions:\n      - done\n",
+			":4: invalid condition in rules[0].conditions[0]",
+		},
+		{
+			"mixed with legacy format",
+			"rules:\n  - max: 1\n    conditions:\n      - \"*\"\n    actions:\n      - done\ndone:\n  max: 1\n  conditions:\n    - merged\n",
+			"rules can not be used with",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), "rules.yml")
+			if err := os.WriteFile(p, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadFile(p)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// retention is how long threads that are no longer seen are kept in the store.
+const retention = 30 * 24 * time.Hour
+
+// Store is a local store recording the notification threads and the actions applied to them.
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	threads map[string]*Thread
+}
+
+// Thread is the state of a notification thread.
+type Thread struct {
+	UpdatedAt  time.Time            `json:"updated_at"`   // Updated time of the thread when it was last seen
+	LastSeenAt time.Time            `json:"last_seen_at"` // Time when the thread was last seen
+	Actions    map[string]time.Time `json:"actions"`      // Updated time of the thread when each action was applied, keyed by action
+}
+
+// Path returns the path of the state file of the profile file.
+func Path(profilePath string) string {
+	return strings.TrimSuffix(profilePath, filepath.Ext(profilePath)) + ".state.json"
+}
+
+// Open opens the store. It is empty if the file does not exist yet.
+func Open(p string) (*Store, error) {
+	s := &Store{
+		path:    p,
+		threads: map[string]*Thread{},
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s.threads); err != nil {
+		return nil, fmt.Errorf("failed to read state file %s: %w", p, err)
+	}
+	return s, nil
+}
+
+// Seen records that the thread was seen.
+func (s *Store) Seen(threadID string, updatedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.threads[threadID]
+	if !ok {
+		t = &Thread{Actions: map[string]time.Time{}}
+		s.threads[threadID] = t
+	}
+	t.UpdatedAt = updatedAt
+	t.LastSeenAt = time.Now()
+}
+
+// Applied reports whether the action has been applied to the thread since it was last updated.
+func (s *Store) Applied(threadID, action string, updatedAt time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.threads[threadID]
+	if !ok {
+		return false
+	}
+	at, ok := t.Actions[action]
+	return ok && !updatedAt.After(at)
+}
+
+// Apply records that the action was applied to the thread.
+func (s *Store) Apply(threadID, action string, updatedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.threads[threadID]
+	if !ok {
+		t = &Thread{UpdatedAt: updatedAt, LastSeenAt: time.Now(), Actions: map[string]time.Time{}}
+		s.threads[threadID] = t
+	}
+	if t.Actions == nil {
+		t.Actions = map[string]time.Time{}
+	}
+	t.Actions[action] = updatedAt
+}
+
+// Save writes the store to the file, dropping threads that have not been seen for a while.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, t := range s.threads {
+		if time.Since(t.LastSeenAt) > retention {
+			delete(s.threads, id)
+		}
+	}
+	b, err := json.Marshal(s.threads)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it so that the state file is never left half-written.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,70 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPath(t *testing.T) {
+	got := Path(filepath.Join("/home/user/.local/share/gh-triage", "work.yml"))
+	want := filepath.Join("/home/user/.local/share/gh-triage", "work.state.json")
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestStore(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "gh-triage", "default.state.json")
+	s, err := Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updatedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.Seen("1", updatedAt)
+	if s.Applied("1", "open", updatedAt) {
+		t.Error("Expected not applied yet")
+	}
+	s.Apply("1", "open", updatedAt)
+	s.Seen("2", updatedAt)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s2.Applied("1", "open", updatedAt) {
+		t.Error("Expected applied")
+	}
+	if s2.Applied("1", "list", updatedAt) {
+		t.Error("Expected other action not applied")
+	}
+	if s2.Applied("1", "open", updatedAt.Add(time.Minute)) {
+		t.Error("Expected not applied after the thread is updated")
+	}
+	if s2.Applied("2", "open", updatedAt) {
+		t.Error("Expected not applied to other thread")
+	}
+}
+
+func TestStoreSaveDropsStaleThreads(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "default.state.json")
+	s, err := Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Apply("1", "open", time.Now())
+	s.threads["1"].LastSeenAt = time.Now().Add(-retention - time.Hour)
+	s.Apply("2", "open", time.Now())
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.threads["1"]; ok {
+		t.Error("Expected stale thread to be dropped")
+	}
+	if _, ok := s.threads["2"]; !ok {
+		t.Error("Expected recent thread to be kept")
+	}
+}